@@ -10,10 +10,15 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/qynonyq/ton_dev_go_hw3/internal/api"
 	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
 	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/sink"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
 )
 
+const shutdownTimeout = 5 * time.Second
+
 func main() {
 	if err := run(); err != nil {
 		log.Fatal(err)
@@ -26,33 +31,95 @@ func run() error {
 		return err
 	}
 
+	var routes *sink.Config
+	if a.Cfg.RoutesFile != "" {
+		routes, err = sink.LoadConfig(a.Cfg.RoutesFile)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	sc, err := scanner.NewScanner(ctx, a.Cfg.NetConfig)
+	broker := stream.NewBroker()
+	go broker.MonitorLag(ctx, a.Cfg.Stream.LagThreshold)
+
+	sc, err := scanner.NewScanner(ctx, a.Cfg, broker)
 	if err != nil {
 		return err
 	}
 	go sc.Listen(ctx)
 
+	var router *sink.Router
+	if routes != nil {
+		router = sink.NewRouter(routes, broker, a.Cfg.SinkOutbox)
+		go router.Run(ctx)
+	}
+
+	var srv *api.Server
+	if a.Cfg.API.Addr != "" {
+		srv = api.NewServer(a.Cfg.API, broker, sc)
+		srv.Start()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
 	logrus.Infof("received %q, shutting down gracefully", sig)
 
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+
 	stopped := make(chan struct{})
 	go func() {
-		sc.Stop()
+		if srv != nil {
+			if err := srv.Shutdown(stopCtx); err != nil {
+				logrus.Errorf("failed to shutdown api server: %s", err)
+			}
+		}
+		sc.Stop(stopCtx)
 		cancel()
 		stopped <- struct{}{}
 	}()
 
+	// scanner gives up unstored blocks once stopCtx is done,
+	// the grace period lets it account for them in the report
 	select {
-	case <-time.After(5 * time.Second):
+	case <-time.After(shutdownTimeout + time.Second):
 		logrus.Info("shutdown timeout expired, scanner stopped")
 	case <-stopped:
 		logrus.Info("scanner gracefully stopped")
 	}
+	logShutdownReport(sc, router)
 
 	return nil
 }
+
+// logShutdownReport logs resume point and work left unfinished.
+func logShutdownReport(sc *scanner.Scanner, router *sink.Router) {
+	r := sc.Report()
+	logrus.WithFields(logrus.Fields{
+		"last_committed": r.LastCommitted,
+		"resume_from":    r.LastCommitted + 1,
+		"uncommitted":    r.Uncommitted,
+		"discarded":      r.Discarded,
+	}).Info("shutdown report: scanner")
+
+	if router == nil {
+		return
+	}
+	for _, q := range router.Report() {
+		entry := logrus.WithFields(logrus.Fields{
+			"sink":        q.Sink,
+			"queued":      q.Queued,
+			"outstanding": q.Outstanding,
+			"dead":        q.Dead,
+		})
+		if q.Queued > 0 || q.Outstanding || q.Dead > 0 {
+			entry.Warn("shutdown report: sink has undelivered payloads")
+			continue
+		}
+		entry.Info("shutdown report: sink")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/xssnick/tonutils-go/address"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	account := flag.String("account", "", "address of account which received the message")
+	lt := flag.Uint64("lt", 0, "logical time of transaction")
+	hash := flag.String("hash", "", "hex hash of transaction")
+	name := flag.String("name", "", "name of vector")
+	description := flag.String("description", "", "description of vector")
+	flag.Parse()
+
+	if *account == "" || *lt == 0 || *hash == "" || *name == "" {
+		return fmt.Errorf("-account, -lt, -hash and -name are required")
+	}
+	addr, err := address.ParseAddr(*account)
+	if err != nil {
+		return fmt.Errorf("invalid -account: %w", err)
+	}
+	txHash, err := hex.DecodeString(*hash)
+	if err != nil {
+		return fmt.Errorf("invalid -hash: %w", err)
+	}
+
+	a, err := app.InitApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sc, err := scanner.NewScanner(ctx, a.Cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer sc.Stop(context.Background())
+
+	v, err := sc.CaptureVector(ctx, addr, *lt, txHash)
+	if err != nil {
+		return err
+	}
+	v.Name, v.Description = *name, *description
+	if len(v.Events) == 0 {
+		log.Printf("no events decoded from %s", v.TxHash)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/ton"
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/sink"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const checkTimeout = 30 * time.Second
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// run validates configuration and everything scanner depends on,
+// it fails if any check fails.
+func run() error {
+	a, err := app.InitApp()
+	if err != nil {
+		return fmt.Errorf("config and database: %w", err)
+	}
+	cfg := a.Cfg
+	logrus.Info("[CHK] PASS config and database connection")
+
+	checks := []check{
+		{name: "db schema", fn: checkSchema},
+		{name: "liteservers", fn: func(ctx context.Context) error {
+			return checkLiteservers(ctx, app.TestnetCfgURL)
+		}},
+	}
+	if cfg.ArchiveCfgURL != "" {
+		checks = append(checks, check{name: "archive liteservers", fn: func(ctx context.Context) error {
+			return checkLiteservers(ctx, cfg.ArchiveCfgURL)
+		}})
+	}
+	for _, dir := range []string{cfg.PluginsDir, cfg.WasmDir} {
+		if dir == "" {
+			continue
+		}
+		checks = append(checks, check{name: "directory " + dir, fn: func(context.Context) error {
+			return checkDir(dir)
+		}})
+	}
+	if cfg.CompactionFile != "" {
+		checks = append(checks, check{name: "compaction config", fn: func(context.Context) error {
+			return scanner.CheckCompactionConfig(cfg.CompactionFile)
+		}})
+	}
+	if cfg.RoutesFile != "" {
+		routes, err := sink.LoadConfig(cfg.RoutesFile)
+		checks = append(checks, check{name: "routes config", fn: func(context.Context) error {
+			return err
+		}})
+		if err == nil {
+			for name, sc := range routes.Sinks {
+				checks = append(checks, check{name: "sink " + name, fn: sc.Ping})
+			}
+		}
+	}
+
+	failed := 0
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		err := c.fn(ctx)
+		cancel()
+		if err != nil {
+			failed++
+			logrus.Errorf("[CHK] FAIL %s: %s", c.name, err)
+			continue
+		}
+		logrus.Infof("[CHK] PASS %s", c.name)
+	}
+
+	logrus.Infof("[CHK] %d passed, %d failed", len(checks)+1-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+
+	return nil
+}
+
+// checkSchema reports tables and columns missing after migrations.
+func checkSchema(context.Context) error {
+	var errs []error
+	m := app.DB.Migrator()
+	for _, model := range storage.Models() {
+		stmt := &gorm.Statement{DB: app.DB}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !m.HasTable(model) {
+			errs = append(errs, fmt.Errorf("table %s is missing", table))
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !m.HasColumn(model, field.DBName) {
+				errs = append(errs, fmt.Errorf("column %s.%s is missing", table, field.DBName))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("run migrate: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// checkLiteservers connects to liteservers of global config
+// and looks up the previous master block.
+func checkLiteservers(ctx context.Context, cfgURL string) error {
+	client := liteclient.NewConnectionPool()
+	defer client.Stop()
+
+	if err := client.AddConnectionsFromConfigUrl(ctx, cfgURL); err != nil {
+		return err
+	}
+	api := ton.NewAPIClient(client)
+
+	master, err := api.GetMasterchainInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get masterchain info: %w", err)
+	}
+	if _, err := api.LookupBlock(ctx, master.Workchain, master.Shard, master.SeqNo-1); err != nil {
+		return fmt.Errorf("failed to lookup block %d: %w", master.SeqNo-1, err)
+	}
+
+	return nil
+}
+
+func checkDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return nil
+}
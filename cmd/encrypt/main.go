@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/fieldcrypt"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/sink"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run encrypts webhook secret read from stdin with ENCRYPTION_KEY for
+// routes file, so it isn't passed in command line.
+func run() error {
+	if _, err := app.InitApp(); err != nil {
+		return err
+	}
+	if !fieldcrypt.Enabled() {
+		return fmt.Errorf("ENCRYPTION_KEY is not set")
+	}
+
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		return fmt.Errorf("failed to read value: %w", err)
+	}
+	encrypted, err := fieldcrypt.Encrypt(strings.TrimRight(value, "\r\n"), sink.SecretScope)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+
+	return nil
+}
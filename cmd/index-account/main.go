@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	addrStr := flag.String("address", "", "account to index, user-friendly or raw")
+	from := flag.String("from", "", "oldest transaction time to index, RFC3339 (defaults to whole history)")
+	limit := flag.Uint("limit", 0, "max number of transactions to index")
+	lt := flag.Uint64("lt", 0, "lt of transaction to resume from")
+	hash := flag.String("hash", "", "hex hash of transaction to resume from, required with -lt")
+	flag.Parse()
+
+	if *addrStr == "" {
+		return fmt.Errorf("-address is required")
+	}
+	addr, err := address.ParseAddr(*addrStr)
+	if err != nil {
+		addr, err = address.ParseRawAddr(*addrStr)
+		if err != nil {
+			return fmt.Errorf("invalid -address: %w", err)
+		}
+	}
+
+	p := scanner.IndexAccountParams{
+		Limit: uint32(*limit),
+		LT:    *lt,
+	}
+	if *from != "" {
+		p.From, err = time.Parse(time.RFC3339, *from)
+		if err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *lt != 0 {
+		p.Hash, err = hex.DecodeString(*hash)
+		if err != nil || len(p.Hash) != 32 {
+			return fmt.Errorf("-hash must be 32 bytes hex with -lt")
+		}
+	}
+
+	a, err := app.InitApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sc, err := scanner.NewScanner(ctx, a.Cfg, nil)
+	if err != nil {
+		return err
+	}
+	// flushes indexed events
+	defer sc.Stop(context.Background())
+
+	txs, err := sc.IndexAccount(ctx, addr, p)
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", addr, err)
+	}
+	logrus.Infof("[ACC] indexed [%d] transactions of %s", txs, addr)
+
+	return nil
+}
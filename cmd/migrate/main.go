@@ -19,10 +19,20 @@ func run() error {
 	}
 
 	dbTx := app.DB.Begin()
-	if err := dbTx.AutoMigrate(&storage.Block{}); err != nil {
+	if err := dbTx.AutoMigrate(storage.Models()...); err != nil {
 		dbTx.Rollback()
 		return err
 	}
+	// event filter indexes were keyed by seq_no, while events are listed by id
+	for _, name := range []string{"idx_events_type_seqno", "idx_events_master_type_seqno", "idx_events_opcode_seqno"} {
+		if !dbTx.Migrator().HasIndex(&storage.Event{}, name) {
+			continue
+		}
+		if err := dbTx.Migrator().DropIndex(&storage.Event{}, name); err != nil {
+			dbTx.Rollback()
+			return err
+		}
+	}
 	if err := dbTx.Commit().Error; err != nil {
 		return err
 	}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	from := flag.Uint("from", 0, "first master block seqno to reparse")
+	to := flag.Uint("to", 0, "last master block seqno to reparse (defaults to -from)")
+	flag.Parse()
+
+	if *from == 0 {
+		return fmt.Errorf("-from is required")
+	}
+	if *to == 0 {
+		*to = *from
+	}
+	if *to < *from {
+		return fmt.Errorf("-to must not be less than -from")
+	}
+
+	a, err := app.InitApp()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sc, err := scanner.NewScanner(ctx, a.Cfg, nil)
+	if err != nil {
+		return err
+	}
+	// flushes reparsed blocks
+	defer sc.Stop(context.Background())
+
+	for seqno := uint32(*from); seqno <= uint32(*to); seqno++ {
+		if err := sc.Reparse(ctx, seqno); err != nil {
+			return fmt.Errorf("failed to reparse block %d: %w", seqno, err)
+		}
+	}
+
+	return nil
+}
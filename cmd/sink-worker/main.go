@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/sink"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run delivers sink jobs stored by scanner with SINK_OUTBOX=true,
+// several workers can run side by side.
+func run() error {
+	sinks := flag.String("sinks", "", "comma separated sinks to deliver (defaults to all)")
+	flag.Parse()
+
+	a, err := app.InitApp()
+	if err != nil {
+		return err
+	}
+	if a.Cfg.RoutesFile == "" {
+		return fmt.Errorf("ROUTES_FILE is required")
+	}
+	routes, err := sink.LoadConfig(a.Cfg.RoutesFile)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if *sinks != "" {
+		names = strings.Split(*sinks, ",")
+	}
+	w, err := sink.NewWorker(routes, names)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	w.Run(ctx)
+	logrus.Info("[SNK] worker stopped")
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	from := flag.Uint("from", 0, "first master block seqno (defaults to the first stored block)")
+	to := flag.Uint("to", 0, "last master block seqno (defaults to the last stored block)")
+	sample := flag.Int("sample", 100, "number of random blocks to check, 0 checks the whole range")
+	flag.Parse()
+
+	a, err := app.InitApp()
+	if err != nil {
+		return err
+	}
+
+	if *from == 0 {
+		var first storage.Block
+		if err := app.DB.First(&first).Error; err != nil {
+			return fmt.Errorf("failed to get first block: %w", err)
+		}
+		*from = uint(first.SeqNo)
+	}
+	if *to == 0 {
+		var last storage.Block
+		if err := app.DB.Last(&last).Error; err != nil {
+			return fmt.Errorf("failed to get last block: %w", err)
+		}
+		*to = uint(last.SeqNo)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sc, err := scanner.NewScanner(ctx, a.Cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer sc.Stop(context.Background())
+
+	report, err := sc.Verify(ctx, uint32(*from), uint32(*to), *sample)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("[VRF] checked [%d] blocks in range [%d-%d]", report.Checked, *from, *to)
+	for _, seqno := range report.Missing {
+		logrus.Errorf("[VRF] block %d is missing", seqno)
+	}
+	for _, m := range report.Mismatches {
+		logrus.Errorf("[VRF] block %d has %d transactions stored, liteserver has %d",
+			m.SeqNo, m.Stored, m.Actual)
+	}
+	if len(report.Unverified) > 0 {
+		logrus.Warnf("[VRF] [%d] blocks have no stored transaction count", len(report.Unverified))
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("verification failed: [%d] missing blocks, [%d] mismatches",
+			len(report.Missing), len(report.Mismatches))
+	}
+	logrus.Info("[VRF] verification passed")
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	subscribeInterval = 6 * time.Second
+	subscribeBurst    = 10
+)
+
+type subscribeAccountRequest struct {
+	Address  string    `json:"address"`
+	FromTime time.Time `json:"from_time"`
+}
+
+// subscribeAccount registers account watch, history is backfilled in
+// background. Backfill walks whole account history since from, so the
+// endpoint requires admin token and is rate limited.
+func (s *Server) subscribeAccount(w http.ResponseWriter, r *http.Request) {
+	if !s.subscribeLimiter.allow() {
+		writeError(w, http.StatusTooManyRequests, errors.New("too many subscriptions, try later"))
+		return
+	}
+
+	var req subscribeAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	addr, err := parseAddr(req.Address)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address: %w", err))
+		return
+	}
+
+	watch, err := s.scanner.SubscribeAccount(r.Context(), addr, req.FromTime)
+	if errors.Is(err, storage.ErrSuppressed) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, watch)
+}
+
+func (s *Server) getAccountWatch(w http.ResponseWriter, r *http.Request) {
+	addr, err := parseAddr(r.PathValue("address"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address: %w", err))
+		return
+	}
+
+	watch, err := s.scanner.AccountWatch(r.Context(), addr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if watch == nil {
+		writeError(w, http.StatusNotFound, errors.New("account is not subscribed"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, watch)
+}
+
+// parseAddr accepts both user-friendly and raw address forms.
+func parseAddr(s string) (*address.Address, error) {
+	addr, err := address.ParseAddr(s)
+	if err == nil {
+		return addr, nil
+	}
+	return address.ParseRawAddr(s)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+type failedBlocksResponse struct {
+	FailedBlocks []storage.FailedBlock `json:"failed_blocks"`
+}
+
+// admin requires bearer token for handler.
+func (s *Server) admin(token string, next http.HandlerFunc) http.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// listFailedBlocks returns dead-letter blocks ordered by seqno,
+// resolved ones only with resolved=true.
+func (s *Server) listFailedBlocks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	db := app.DB.Model(&storage.FailedBlock{})
+	if q.Get("resolved") != "true" {
+		db = db.Where("resolved_at IS NULL")
+	}
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %q", v))
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	blocks := make([]storage.FailedBlock, 0, limit)
+	if err := db.Order("seq_no").Limit(limit).Find(&blocks).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, failedBlocksResponse{FailedBlocks: blocks})
+}
+
+// rerunFailedBlock processes failed block again with handlers from
+// request body, empty body runs all handlers.
+func (s *Server) rerunFailedBlock(w http.ResponseWriter, r *http.Request) {
+	seqno, err := strconv.ParseUint(r.PathValue("seqno"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid seqno: %w", err))
+		return
+	}
+	var p scanner.RerunParams
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	err = s.scanner.Rerun(r.Context(), uint32(seqno), p)
+	if errors.Is(err, scanner.ErrUnknownHandler) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, errors.New("block is not in dead-letter table"))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var failed storage.FailedBlock
+	if err := app.DB.First(&failed, "seq_no = ?", seqno).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, failed)
+}
+
+// purgeAddress removes all indexed data of address on request of its
+// owner, deletion is recorded to audit table and log.
+func (s *Server) purgeAddress(w http.ResponseWriter, r *http.Request) {
+	addr, err := parseAddr(r.PathValue("address"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address: %w", err))
+		return
+	}
+
+	d, err := storage.PurgeAddress(app.DB.WithContext(r.Context()), addr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if s.cache != nil {
+		s.cache.clear()
+	}
+	logrus.Infof("[API] purged address data [deletion=%d] [hash=%s]: %d events, %d shadow events, %d summaries, %d watches, %d sink job events",
+		d.ID, d.AddressHash, d.Events, d.ShadowEvents, d.Summaries, d.Watches, d.SinkJobEvents)
+
+	writeJSON(w, http.StatusOK, d)
+}
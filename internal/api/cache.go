@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const (
+	cacheConsumer   = "api_cache"
+	cacheMaxEntries = 10000
+)
+
+// cache keeps responses of read queries until the next block commit
+// or ttl expiration, so polling dashboards don't hit the db.
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// incremented on every clear, responses read before it are not cached
+	generation uint64
+}
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+
+	return e.body, true
+}
+
+// gen returns current generation, it must be taken before the db read.
+func (c *cache) gen() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// put caches body read in generation gen, unless cache was cleared since.
+func (c *cache) put(key string, body []byte, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.generation {
+		return
+	}
+	if len(c.entries) >= cacheMaxEntries {
+		// hot queries are cached again quickly
+		c.entries = make(map[string]cacheEntry)
+	}
+	c.entries[key] = cacheEntry{body: body, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cacheEntry)
+	c.generation++
+}
+
+// invalidate clears cache on every committed batch until ctx is done.
+func (c *cache) invalidate(ctx context.Context, broker *stream.Broker) {
+	for {
+		sub := broker.Subscribe(cacheConsumer, 0)
+		dropped := c.consume(ctx, sub)
+		broker.Unsubscribe(sub)
+		if !dropped {
+			return
+		}
+		// batches could be missed
+		c.clear()
+	}
+}
+
+func (c *cache) consume(ctx context.Context, sub *stream.Subscription) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case batch, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			c.clear()
+			sub.Delivered(batch.Head)
+		}
+	}
+}
+
+// cached serves successful responses of h from cache, keyed by url.
+func (s *Server) cached(h http.HandlerFunc) http.HandlerFunc {
+	if s.cache == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.String()
+		if body, ok := s.cache.get(key); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+			return
+		}
+
+		gen := s.cache.gen()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		w.Header().Set("X-Cache", "MISS")
+		h(rec, r)
+		if rec.status == http.StatusOK {
+			s.cache.put(key, rec.body.Bytes(), gen)
+		}
+	}
+}
+
+// responseRecorder copies response body while writing it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
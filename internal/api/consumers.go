@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+type consumersResponse struct {
+	Consumers []stream.ConsumerLag `json:"consumers"`
+}
+
+func (s *Server) listConsumers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, consumersResponse{Consumers: s.broker.Lags()})
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type eventFilter struct {
+	Type         string
+	Opcode       *uint32
+	JettonMaster string
+	MinAmount    string
+	MaxAmount    string
+	Success      *bool
+	WithDeleted  bool
+	AfterID      uint64
+	Limit        int
+}
+
+type eventsResponse struct {
+	Events []storage.Event `json:"events"`
+}
+
+// listEvents returns events ordered by id, use after_id for pagination.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	f, err := parseEventFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events := make([]storage.Event, 0, f.Limit)
+	if err := f.apply(app.DB).Find(&events).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, eventsResponse{Events: events})
+}
+
+func parseEventFilter(q url.Values) (eventFilter, error) {
+	f := eventFilter{
+		Type:         q.Get("type"),
+		JettonMaster: q.Get("jetton_master"),
+		Limit:        defaultLimit,
+	}
+
+	if f.Type != "" {
+		if !storage.IsEventType(f.Type) {
+			return f, fmt.Errorf("unknown event type %q", f.Type)
+		}
+	}
+	if v := q.Get("opcode"); v != "" {
+		// accepts both decimal and 0x-prefixed hex
+		op, err := strconv.ParseUint(v, 0, 32)
+		if err != nil {
+			return f, fmt.Errorf("invalid opcode: %w", err)
+		}
+		op32 := uint32(op)
+		f.Opcode = &op32
+	}
+	for param, dst := range map[string]*string{
+		"min_amount": &f.MinAmount,
+		"max_amount": &f.MaxAmount,
+	} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		if _, ok := new(big.Int).SetString(v, 10); !ok {
+			return f, fmt.Errorf("invalid %s: %q", param, v)
+		}
+		*dst = v
+	}
+	if v := q.Get("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid success: %w", err)
+		}
+		f.Success = &success
+	}
+	if v := q.Get("include_deleted"); v != "" {
+		withDeleted, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid include_deleted: %w", err)
+		}
+		f.WithDeleted = withDeleted
+	}
+	if v := q.Get("after_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("invalid after_id: %w", err)
+		}
+		f.AfterID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return f, fmt.Errorf("invalid limit: %q", v)
+		}
+		f.Limit = min(limit, maxLimit)
+	}
+
+	return f, nil
+}
+
+func (f eventFilter) apply(db *gorm.DB) *gorm.DB {
+	q := db.Model(&storage.Event{})
+	if f.WithDeleted {
+		q = q.Unscoped()
+	}
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.Opcode != nil {
+		q = q.Where("opcode = ?", *f.Opcode)
+	}
+	if f.JettonMaster != "" {
+		q = q.Where("jetton_master = ?", f.JettonMaster)
+	}
+	if f.MinAmount != "" {
+		q = q.Where("amount >= ?::numeric", f.MinAmount)
+	}
+	if f.MaxAmount != "" {
+		q = q.Where("amount <= ?::numeric", f.MaxAmount)
+	}
+	if f.Success != nil {
+		q = q.Where("success = ?", *f.Success)
+	}
+	if f.AfterID > 0 {
+		q = q.Where("id > ?", f.AfterID)
+	}
+
+	return q.Order("id").Limit(f.Limit)
+}
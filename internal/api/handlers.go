@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+)
+
+type handlersResponse struct {
+	Handlers []scanner.HandlerStats `json:"handlers"`
+}
+
+func (s *Server) listHandlers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, handlersResponse{Handlers: s.scanner.HandlerStats()})
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket of burst tokens refilled one per interval.
+type rateLimiter struct {
+	interval time.Duration
+	burst    float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(interval time.Duration, burst int) *rateLimiter {
+	return &rateLimiter{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// allow takes token if there is one.
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+
+	return true
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+type Server struct {
+	srv     *http.Server
+	broker  *stream.Broker
+	scanner *scanner.Scanner
+	cache   *cache
+	// limits account subscriptions, each can start history backfill
+	subscribeLimiter *rateLimiter
+}
+
+func NewServer(cfg app.API, broker *stream.Broker, sc *scanner.Scanner) *Server {
+	// cancelled on shutdown to finish long-lived streams
+	ctx, cancel := context.WithCancel(context.Background())
+	mux := http.NewServeMux()
+	s := &Server{
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		},
+		broker:           broker,
+		scanner:          sc,
+		subscribeLimiter: newRateLimiter(subscribeInterval, subscribeBurst),
+	}
+	s.srv.RegisterOnShutdown(cancel)
+	if cfg.CacheTTL > 0 {
+		s.cache = newCache(cfg.CacheTTL)
+		go s.cache.invalidate(ctx, broker)
+	}
+
+	mux.HandleFunc("GET /events", s.cached(s.listEvents))
+	mux.HandleFunc("GET /events/stream", s.streamEvents)
+	mux.HandleFunc("GET /summaries", s.cached(s.listSummaries))
+	mux.HandleFunc("GET /consumers", s.listConsumers)
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("GET /stats", s.listStats)
+	mux.HandleFunc("GET /handlers", s.listHandlers)
+	mux.HandleFunc("GET /accounts/subscriptions/{address}", s.getAccountWatch)
+	mux.HandleFunc("GET /openapi.json", s.openAPI)
+	if cfg.AdminToken != "" {
+		mux.HandleFunc("GET /admin/failed-blocks", s.admin(cfg.AdminToken, s.listFailedBlocks))
+		mux.HandleFunc("POST /admin/failed-blocks/{seqno}/rerun", s.admin(cfg.AdminToken, s.rerunFailedBlock))
+		mux.HandleFunc("DELETE /admin/addresses/{address}", s.admin(cfg.AdminToken, s.purgeAddress))
+		mux.HandleFunc("POST /accounts/subscriptions", s.admin(cfg.AdminToken, s.subscribeAccount))
+	}
+
+	return s
+}
+
+func (s *Server) Start() {
+	go func() {
+		logrus.Infof("[API] listening on %s", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("[API] server stopped: %s", err)
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
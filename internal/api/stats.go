@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const defaultStatsPeriod = time.Hour
+
+type statsResponse struct {
+	Stats []storage.Stat `json:"stats"`
+}
+
+// listStats returns per-minute scanner throughput between from and to
+// (RFC 3339), last hour by default.
+func (s *Server) listStats(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultStatsPeriod)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+			return
+		}
+		from = t
+	}
+
+	stats := make([]storage.Stat, 0)
+	err := app.DB.
+		Where("minute >= ? AND minute <= ?", from, to).
+		Order("minute").
+		Find(&stats).Error
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statsResponse{Stats: stats})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scanner"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scheduler"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const statusGapsLimit = 100
+
+type statusResponse struct {
+	LastSeqNo       uint32                     `json:"last_seqno"`
+	LastProcessedAt time.Time                  `json:"last_processed_at"`
+	Gaps            []storage.Gap              `json:"gaps"`
+	Concurrency     []scanner.ConcurrencyStats `json:"concurrency"`
+	Jobs            []scheduler.JobStatus      `json:"jobs"`
+}
+
+func (s *Server) status(w http.ResponseWriter, _ *http.Request) {
+	resp := statusResponse{
+		Concurrency: s.scanner.Concurrency(),
+		Jobs:        s.scanner.Jobs(),
+	}
+
+	var last storage.Block
+	err := app.DB.Last(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp.LastSeqNo = last.SeqNo
+	resp.LastProcessedAt = last.ProcessedAt
+
+	resp.Gaps, err = storage.FindGaps(app.DB, statusGapsLimit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
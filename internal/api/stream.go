@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const (
+	backfillPageSize = 1000
+	keepAliveEvery   = 15 * time.Second
+)
+
+var errSubscriberDropped = errors.New("subscriber is too slow, reconnect with last token")
+
+// streamEvents streams events as server-sent events, id of every message is
+// a resume token. Clients reconnecting with token query parameter or
+// Last-Event-ID header get all events after the token from db first and
+// then continue with live events, without gaps.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	raw := r.URL.Query().Get("token")
+	if raw == "" {
+		raw = r.Header.Get("Last-Event-ID")
+	}
+	var (
+		last    stream.Token
+		hasLast bool
+	)
+	if raw != "" {
+		token, err := stream.ParseToken(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		last, hasLast = token, true
+	}
+
+	consumer := r.URL.Query().Get("consumer")
+	if consumer == "" {
+		consumer = r.RemoteAddr
+	}
+
+	// subscribe before backfill, so events committed meanwhile aren't lost
+	var delivered uint32
+	if hasLast {
+		delivered = last.SeqNo
+	}
+	sub := s.broker.Subscribe(consumer, delivered)
+	defer s.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if hasLast {
+		var err error
+		last, err = s.backfill(w, sub, last)
+		if err != nil {
+			writeSSEError(w, err)
+			return
+		}
+		flusher.Flush()
+	}
+
+	ticker := time.NewTicker(keepAliveEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case batch, ok := <-sub.C:
+			if !ok {
+				writeSSEError(w, errSubscriberDropped)
+				return
+			}
+			for _, e := range batch.Events {
+				token := stream.TokenOf(e)
+				// already sent during backfill
+				if hasLast && !last.Less(token) {
+					continue
+				}
+				if err := writeSSEEvent(w, e); err != nil {
+					return
+				}
+				last, hasLast = token, true
+			}
+			for _, c := range batch.Corrections {
+				if err := writeSSECorrection(w, c); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+			sub.Delivered(batch.Head)
+		}
+	}
+}
+
+// backfill writes stored events after token and returns the last written one.
+func (s *Server) backfill(w http.ResponseWriter, sub *stream.Subscription, after stream.Token) (stream.Token, error) {
+	for {
+		events, err := stream.EventsAfter(after, backfillPageSize)
+		if err != nil {
+			return after, err
+		}
+
+		for _, e := range events {
+			if err := writeSSEEvent(w, e); err != nil {
+				return after, err
+			}
+			after = stream.TokenOf(e)
+		}
+		sub.Delivered(after.SeqNo)
+
+		if len(events) < backfillPageSize {
+			return after, nil
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, e storage.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", stream.TokenOf(e), e.Type, data)
+	return err
+}
+
+// writeSSECorrection doesn't set event id, corrections don't move the resume token.
+func writeSSECorrection(w http.ResponseWriter, c stream.Correction) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: correction\ndata: %s\n\n", data)
+	return err
+}
+
+func writeSSEError(w http.ResponseWriter, err error) {
+	data, _ := json.Marshal(errorResponse{Error: err.Error()})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+}
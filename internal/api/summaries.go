@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+type summariesResponse struct {
+	Summaries []storage.EventSummary `json:"summaries"`
+}
+
+// listSummaries returns summaries of compacted addresses ordered by id,
+// use after_id for pagination.
+func (s *Server) listSummaries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	db := app.DB.Model(&storage.EventSummary{})
+
+	if v := q.Get("label"); v != "" {
+		db = db.Where("label = ?", v)
+	}
+	if v := q.Get("type"); v != "" {
+		db = db.Where("type = ?", v)
+	}
+	if v := q.Get("after_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid after_id: %w", err))
+			return
+		}
+		db = db.Where("id > ?", id)
+	}
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %q", v))
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	summaries := make([]storage.EventSummary, 0, limit)
+	if err := db.Order("id").Limit(limit).Find(&summaries).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summariesResponse{Summaries: summaries})
+}
@@ -1,5 +1,7 @@
 package app
 
+import "github.com/qynonyq/ton_dev_go_hw3/internal/fieldcrypt"
+
 type App struct {
 	Cfg *Cfg
 }
@@ -14,6 +16,10 @@ func InitApp() (*App, error) {
 		return nil, err
 	}
 
+	if err := fieldcrypt.SetKey(cfg.EncryptionKey, cfg.OldEncryptionKeys...); err != nil {
+		return nil, err
+	}
+
 	if err := initDatabase(cfg.Postgres); err != nil {
 		return nil, err
 	}
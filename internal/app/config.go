@@ -1,14 +1,24 @@
 package app
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/xssnick/tonutils-go/liteclient"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/fieldcrypt"
 )
 
 const (
+	defaultGapCheckInterval = 10 * time.Minute
+	defaultStatsRetention   = 7 * 24 * time.Hour
+	defaultBackfillDepth    = 30 * 24 * time.Hour
+	defaultMaxConcurrency   = 64
+
 	MainnetCfgURL = "https://ton-blockchain.github.io/global.config.json"
 	TestnetCfgURL = "https://ton-blockchain.github.io/testnet-global.config.json"
 )
@@ -19,6 +29,80 @@ type (
 		Postgres  Postgres
 		NetConfig *liteclient.GlobalConfig
 		Wallet    Wallet
+		// optional global config with archive liteservers
+		ArchiveCfgURL string
+		Discovery     Discovery
+		API           API
+		Stream        Stream
+		// directory with handler plugins (.so)
+		PluginsDir string
+		// directory with sandboxed wasm decoders (.wasm)
+		WasmDir string
+		// sinks and event routing rules, see sink.Config
+		RoutesFile string
+		// how often missing blocks are looked up and filled, 0 disables
+		GapCheckInterval time.Duration
+		// how long per-minute throughput stats are kept, 0 keeps forever
+		StatsRetention time.Duration
+		// config params checked for changes in every master block
+		ConfigParams []int32
+		// handlers which errors fail the block, errors of others are only logged
+		CriticalHandlers []string
+		// handlers which outputs are only recorded to shadow_events
+		ShadowHandlers []string
+		// addresses which events are stored as per-block summaries,
+		// see scanner.CompactionConfig
+		CompactionFile string
+		// history of subscribed accounts older than this isn't
+		// backfilled
+		AccountBackfillDepth time.Duration
+		// identical jetton notifications within this window are
+		// suppressed, 0 disables
+		DedupWindow time.Duration
+		// max time of master block processing, diagnostics are dumped
+		// to DiagnosticsDir and the block is retried, 0 disables
+		BlockTimeout   time.Duration
+		DiagnosticsDir string
+		// on-disk cache of block shards and transaction lists for
+		// retries of failed blocks, disabled if empty
+		BlockCacheDir string
+		// upper bound of adaptive transaction fetch and parse pools
+		MaxConcurrency int
+		// AES key of encrypted db columns and config values,
+		// see fieldcrypt, encryption is disabled if empty
+		EncryptionKey []byte
+		// previous keys, values encrypted with them are still
+		// decrypted after key rotation
+		OldEncryptionKeys [][]byte
+		// payloads are stored as sink jobs for cmd/sink-worker
+		// instead of being delivered by the scanner process
+		SinkOutbox bool
+		// periodic jobs turned off, see Scanner.Jobs
+		DisabledJobs []string
+	}
+
+	Stream struct {
+		// consumers lagging behind the head for more blocks are reported
+		LagThreshold uint32
+	}
+
+	API struct {
+		// listen address, api is disabled if empty
+		Addr string
+		// read responses are cached until the next commit, but not
+		// longer than this, 0 disables cache
+		CacheTTL time.Duration
+		// bearer token of admin endpoints, they are disabled if empty
+		AdminToken string
+	}
+
+	// Discovery configures lookup of liteservers through DHT
+	// when connection pool is degraded.
+	Discovery struct {
+		Enabled bool
+		// additional liteserver public keys (base64) to resolve in DHT
+		Keys     []string
+		MinAlive int
 	}
 
 	Wallet struct {
@@ -41,8 +125,138 @@ func initConfig() (*Cfg, error) {
 		return nil, err
 	}
 
+	discovery, err := initDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := initStream()
+	if err != nil {
+		return nil, err
+	}
+
+	gapCheckInterval := defaultGapCheckInterval
+	if v := os.Getenv("GAP_CHECK_INTERVAL"); v != "" {
+		gapCheckInterval, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GAP_CHECK_INTERVAL: %w", err)
+		}
+	}
+
+	statsRetention := defaultStatsRetention
+	if v := os.Getenv("STATS_RETENTION"); v != "" {
+		statsRetention, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STATS_RETENTION: %w", err)
+		}
+	}
+
+	backfillDepth := defaultBackfillDepth
+	if v := os.Getenv("ACCOUNT_BACKFILL_DEPTH"); v != "" {
+		backfillDepth, err = time.ParseDuration(v)
+		if err != nil || backfillDepth <= 0 {
+			return nil, fmt.Errorf("invalid ACCOUNT_BACKFILL_DEPTH: %q", v)
+		}
+	}
+
+	var dedupWindow time.Duration
+	if v := os.Getenv("DEDUP_WINDOW"); v != "" {
+		dedupWindow, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEDUP_WINDOW: %w", err)
+		}
+	}
+
+	var blockTimeout time.Duration
+	if v := os.Getenv("BLOCK_TIMEOUT"); v != "" {
+		blockTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BLOCK_TIMEOUT: %w", err)
+		}
+	}
+	diagnosticsDir := os.Getenv("DIAGNOSTICS_DIR")
+	if diagnosticsDir == "" {
+		diagnosticsDir = os.TempDir()
+	}
+
+	maxConcurrency := defaultMaxConcurrency
+	if v := os.Getenv("MAX_CONCURRENCY"); v != "" {
+		maxConcurrency, err = strconv.Atoi(v)
+		if err != nil || maxConcurrency < 1 {
+			return nil, fmt.Errorf("invalid MAX_CONCURRENCY: %q", v)
+		}
+	}
+
+	var encryptionKey []byte
+	if v := os.Getenv("ENCRYPTION_KEY"); v != "" {
+		encryptionKey, err = fieldcrypt.ParseKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEY: %w", err)
+		}
+	}
+	var oldEncryptionKeys [][]byte
+	for _, v := range strings.Fields(os.Getenv("OLD_ENCRYPTION_KEYS")) {
+		key, err := fieldcrypt.ParseKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OLD_ENCRYPTION_KEYS: %w", err)
+		}
+		oldEncryptionKeys = append(oldEncryptionKeys, key)
+	}
+	if len(oldEncryptionKeys) > 0 && encryptionKey == nil {
+		return nil, fmt.Errorf("OLD_ENCRYPTION_KEYS are set without ENCRYPTION_KEY")
+	}
+
+	var sinkOutbox bool
+	if v := os.Getenv("SINK_OUTBOX"); v != "" {
+		sinkOutbox, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SINK_OUTBOX: %w", err)
+		}
+	}
+
+	configParams, err := initConfigParams()
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheTTL time.Duration
+	if v := os.Getenv("API_CACHE_TTL"); v != "" {
+		cacheTTL, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API_CACHE_TTL: %w", err)
+		}
+	}
+
 	cfg := Cfg{
-		LogLevel: os.Getenv("LOG_LEVEL"),
+		LogLevel:      os.Getenv("LOG_LEVEL"),
+		ArchiveCfgURL: os.Getenv("ARCHIVE_CONFIG_URL"),
+		Discovery:     discovery,
+		API: API{
+			Addr:       os.Getenv("API_ADDR"),
+			CacheTTL:   cacheTTL,
+			AdminToken: os.Getenv("API_ADMIN_TOKEN"),
+		},
+		Stream:     stream,
+		PluginsDir: os.Getenv("PLUGINS_DIR"),
+		WasmDir:    os.Getenv("WASM_DIR"),
+		RoutesFile: os.Getenv("ROUTES_FILE"),
+
+		GapCheckInterval:     gapCheckInterval,
+		StatsRetention:       statsRetention,
+		ConfigParams:         configParams,
+		CriticalHandlers:     strings.Fields(os.Getenv("CRITICAL_HANDLERS")),
+		ShadowHandlers:       strings.Fields(os.Getenv("SHADOW_HANDLERS")),
+		CompactionFile:       os.Getenv("COMPACTION_FILE"),
+		AccountBackfillDepth: backfillDepth,
+		DedupWindow:          dedupWindow,
+		BlockTimeout:         blockTimeout,
+		DiagnosticsDir:       diagnosticsDir,
+		BlockCacheDir:        os.Getenv("BLOCK_CACHE_DIR"),
+		MaxConcurrency:       maxConcurrency,
+		EncryptionKey:        encryptionKey,
+		OldEncryptionKeys:    oldEncryptionKeys,
+		SinkOutbox:           sinkOutbox,
+		DisabledJobs:         strings.Fields(os.Getenv("JOBS_DISABLED")),
 		Wallet: Wallet{
 			Seed: strings.Split(os.Getenv("SEED"), " "),
 		},
@@ -59,3 +273,65 @@ func initConfig() (*Cfg, error) {
 
 	return &cfg, nil
 }
+
+func initDiscovery() (Discovery, error) {
+	var (
+		d   Discovery
+		err error
+	)
+
+	if v := os.Getenv("LS_DISCOVERY"); v != "" {
+		d.Enabled, err = strconv.ParseBool(v)
+		if err != nil {
+			return d, fmt.Errorf("invalid LS_DISCOVERY: %w", err)
+		}
+	}
+	if v := os.Getenv("LS_DISCOVERY_MIN_ALIVE"); v != "" {
+		d.MinAlive, err = strconv.Atoi(v)
+		if err != nil {
+			return d, fmt.Errorf("invalid LS_DISCOVERY_MIN_ALIVE: %w", err)
+		}
+	}
+	d.Keys = strings.Fields(os.Getenv("LS_DISCOVERY_KEYS"))
+
+	return d, nil
+}
+
+func initStream() (Stream, error) {
+	const defaultLagThreshold = 100
+
+	s := Stream{LagThreshold: defaultLagThreshold}
+	if v := os.Getenv("STREAM_LAG_THRESHOLD"); v != "" {
+		threshold, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return s, fmt.Errorf("invalid STREAM_LAG_THRESHOLD: %w", err)
+		}
+		s.LagThreshold = uint32(threshold)
+	}
+
+	return s, nil
+}
+
+// initConfigParams parses space separated CONFIG_PARAMS, "none" disables
+// config monitoring. By default workchains, storage, gas and forwarding
+// prices are monitored.
+func initConfigParams() ([]int32, error) {
+	v := os.Getenv("CONFIG_PARAMS")
+	switch v {
+	case "":
+		return []int32{12, 18, 20, 21, 24, 25}, nil
+	case "none":
+		return nil, nil
+	}
+
+	var params []int32
+	for _, f := range strings.Fields(v) {
+		p, err := strconv.ParseInt(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONFIG_PARAMS: %w", err)
+		}
+		params = append(params, int32(p))
+	}
+
+	return params, nil
+}
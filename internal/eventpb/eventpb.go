@@ -0,0 +1,123 @@
+// Package eventpb encodes events in protobuf wire format according to
+// proto/events.proto. It's written by hand to avoid generated code and
+// protobuf runtime dependency, field numbers must match the proto file.
+package eventpb
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const ContentType = "application/x-protobuf"
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// MarshalPayload encodes ton.scanner.v1.Payload.
+func MarshalPayload(events []storage.Event, corrections []stream.Correction) []byte {
+	var b []byte
+	for _, e := range events {
+		b = appendMessage(b, 1, appendEvent(nil, e))
+	}
+	for _, c := range corrections {
+		b = appendMessage(b, 2, appendCorrection(nil, c))
+	}
+
+	return b
+}
+
+// MarshalEvent encodes ton.scanner.v1.Event.
+func MarshalEvent(e storage.Event) []byte {
+	return appendEvent(nil, e)
+}
+
+func appendCorrection(b []byte, c stream.Correction) []byte {
+	b = appendUint(b, 1, uint64(c.SeqNo))
+	for _, e := range c.Superseded {
+		b = appendMessage(b, 2, appendEvent(nil, e))
+	}
+	for _, e := range c.Events {
+		b = appendMessage(b, 3, appendEvent(nil, e))
+	}
+
+	return b
+}
+
+func appendEvent(b []byte, e storage.Event) []byte {
+	b = appendUint(b, 1, e.ID)
+	b = appendString(b, 2, e.Type)
+	b = appendUint(b, 3, uint64(e.SeqNo))
+	b = appendUint(b, 4, uint64(e.EventIndex))
+	b = appendUint(b, 5, e.LT)
+	b = appendString(b, 6, e.TxHash)
+	b = appendUint(b, 7, uint64(e.Opcode))
+	b = appendString(b, 8, e.JettonMaster)
+	b = appendString(b, 9, e.JettonWalletCode)
+	b = appendString(b, 10, e.JettonWalletType)
+	b = appendString(b, 11, e.Sender)
+	b = appendString(b, 12, e.Recipient)
+	b = appendString(b, 13, e.NftItem)
+	b = appendString(b, 14, e.Amount)
+	b = appendString(b, 15, string(e.Comment))
+	b = appendString(b, 16, e.Payload)
+	if e.ConfigParam != nil {
+		// optional field is present even if zero, negative int32 is sign extended
+		b = appendTag(b, 17, wireVarint)
+		b = binary.AppendUvarint(b, uint64(int64(*e.ConfigParam)))
+	}
+	b = appendString(b, 18, e.ConfigValue)
+	if e.Success {
+		b = appendUint(b, 19, 1)
+	}
+	b = appendUint(b, 20, uint64(e.Revision))
+	b = appendString(b, 23, e.CustomPayload)
+	b = appendUint(b, 24, uint64(e.UTime))
+	if !e.CreatedAt.IsZero() {
+		b = appendMessage(b, 21, appendTimestamp(nil, e.CreatedAt))
+	}
+	if e.DeletedAt.Valid {
+		b = appendMessage(b, 22, appendTimestamp(nil, e.DeletedAt.Time))
+	}
+
+	return b
+}
+
+// appendTimestamp encodes google.protobuf.Timestamp.
+func appendTimestamp(b []byte, t time.Time) []byte {
+	b = appendUint(b, 1, uint64(t.Unix()))
+	return appendUint(b, 2, uint64(t.Nanosecond()))
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendUint skips zero values like proto3 does.
+func appendUint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendMessage writes embedded message even if it's empty.
+func appendMessage(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
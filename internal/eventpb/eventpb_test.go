@@ -0,0 +1,269 @@
+package eventpb
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+type protoField struct {
+	name string
+	typ  string
+}
+
+var (
+	messageRe = regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`)
+	fieldRe   = regexp.MustCompile(`(?m)^\s*(?:optional |repeated )?([\w.]+) (\w+) = (\d+);`)
+)
+
+// protoMessages reads field numbers of messages declared in events.proto.
+func protoMessages(t *testing.T) map[string]map[protowire.Number]protoField {
+	t.Helper()
+
+	src, err := os.ReadFile("../../proto/events.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(map[string]map[protowire.Number]protoField)
+	for _, m := range messageRe.FindAllStringSubmatch(string(src), -1) {
+		fields := make(map[protowire.Number]protoField)
+		for _, f := range fieldRe.FindAllStringSubmatch(m[2], -1) {
+			num, err := strconv.Atoi(f[3])
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields[protowire.Number(num)] = protoField{name: f[2], typ: f[1]}
+		}
+		messages[m[1]] = fields
+	}
+
+	return messages
+}
+
+// decode parses message b by its proto declaration into field name to
+// value, embedded messages are decoded recursively into slices.
+func decode(messages map[string]map[protowire.Number]protoField, msg string, b []byte) (map[string]any, error) {
+	fields, ok := messages[msg]
+	if !ok {
+		if msg != "google.protobuf.Timestamp" {
+			return nil, fmt.Errorf("unknown message %s", msg)
+		}
+		fields = map[protowire.Number]protoField{1: {"seconds", "int64"}, 2: {"nanos", "int32"}}
+	}
+
+	values := make(map[string]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		f, ok := fields[num]
+		if !ok {
+			return nil, fmt.Errorf("%s: field %d is not in proto", msg, num)
+		}
+
+		switch f.typ {
+		case "string":
+			if typ != protowire.BytesType {
+				return nil, fmt.Errorf("%s.%s: wire type %d", msg, f.name, typ)
+			}
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			values[f.name] = v
+			b = b[n:]
+		case "uint64", "uint32", "int64", "int32", "bool":
+			if typ != protowire.VarintType {
+				return nil, fmt.Errorf("%s.%s: wire type %d", msg, f.name, typ)
+			}
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			switch f.typ {
+			case "int32":
+				values[f.name] = int64(int32(v))
+			case "int64":
+				values[f.name] = int64(v)
+			case "bool":
+				values[f.name] = protowire.DecodeBool(v)
+			default:
+				values[f.name] = v
+			}
+			b = b[n:]
+		default:
+			if typ != protowire.BytesType {
+				return nil, fmt.Errorf("%s.%s: wire type %d", msg, f.name, typ)
+			}
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			nested, err := decode(messages, f.typ, v)
+			if err != nil {
+				return nil, err
+			}
+			list, _ := values[f.name].([]map[string]any)
+			values[f.name] = append(list, nested)
+			b = b[n:]
+		}
+	}
+
+	return values, nil
+}
+
+func testEvent(seqno uint32) storage.Event {
+	param := int32(-999)
+	return storage.Event{
+		ID:               42,
+		Type:             storage.EventTypeJettonTransfer,
+		SeqNo:            seqno,
+		EventIndex:       3,
+		LT:               47000000000001,
+		UTime:            1699999999,
+		TxHash:           strings.Repeat("ab", 32),
+		Opcode:           0x7362d09c,
+		JettonMaster:     "EQAKNnuSzwsDff2Jlg7oMtVvf8FRaBu0HlNpDndvV4aZimBb",
+		JettonWalletCode: strings.Repeat("cd", 32),
+		JettonWalletType: "standard",
+		Sender:           "EQBmXQaY28j7la_CXDpNnPKA2HpYW3mZJDymAI_QMliXX-IG",
+		Recipient:        "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs",
+		NftItem:          "EQD2NmD_lH5f5u1Kj3KfGyTvhZSX0Eg6qp2a5IQUKXxOG21n",
+		Amount:           "1000000000000000000000000000000",
+		Comment:          "gm",
+		Payload:          "te6cckEBAQEAAgAAAEysuc0=",
+		CustomPayload:    "te6cckEBAQEABgAACAAAAAHEpnRp",
+		ConfigParam:      &param,
+		ConfigValue:      "te6cckEBAQEAAgAAAEysuc0=",
+		Success:          true,
+		Revision:         2,
+		CreatedAt:        time.Unix(1700000000, 123456789),
+		DeletedAt:        gorm.DeletedAt{Time: time.Unix(1700000100, 5), Valid: true},
+	}
+}
+
+func timestamp(t time.Time) []map[string]any {
+	return []map[string]any{{"seconds": t.Unix(), "nanos": int64(t.Nanosecond())}}
+}
+
+func checkEvent(t *testing.T, messages map[string]map[protowire.Number]protoField, got map[string]any, e storage.Event) {
+	t.Helper()
+
+	want := map[string]any{
+		"id":                 e.ID,
+		"type":               e.Type,
+		"seqno":              uint64(e.SeqNo),
+		"event_index":        uint64(e.EventIndex),
+		"lt":                 e.LT,
+		"tx_hash":            e.TxHash,
+		"opcode":             uint64(e.Opcode),
+		"jetton_master":      e.JettonMaster,
+		"jetton_wallet_code": e.JettonWalletCode,
+		"jetton_wallet_type": e.JettonWalletType,
+		"sender":             e.Sender,
+		"recipient":          e.Recipient,
+		"nft_item":           e.NftItem,
+		"amount":             e.Amount,
+		"comment":            string(e.Comment),
+		"payload":            e.Payload,
+		"custom_payload":     e.CustomPayload,
+		"utime":              uint64(e.UTime),
+		"config_param":       int64(*e.ConfigParam),
+		"config_value":       e.ConfigValue,
+		"success":            e.Success,
+		"revision":           uint64(e.Revision),
+		"created_at":         timestamp(e.CreatedAt),
+		"deleted_at":         timestamp(e.DeletedAt.Time),
+	}
+	// every field of proto is set in test event, so missing encoding is caught
+	for _, f := range messages["Event"] {
+		if _, ok := want[f.name]; !ok {
+			t.Errorf("Event.%s is not checked", f.name)
+		}
+	}
+	for name, w := range want {
+		if g := fmt.Sprint(got[name]); g != fmt.Sprint(w) {
+			t.Errorf("Event.%s: expected %v, got %s", name, w, g)
+		}
+	}
+}
+
+func TestMarshalEvent(t *testing.T) {
+	messages := protoMessages(t)
+	e := testEvent(100)
+
+	got, err := decode(messages, "Event", MarshalEvent(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvent(t, messages, got, e)
+}
+
+func TestMarshalEventZero(t *testing.T) {
+	messages := protoMessages(t)
+	zero := int32(0)
+
+	got, err := decode(messages, "Event", MarshalEvent(storage.Event{ConfigParam: &zero}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// optional field is present even if zero, others are omitted
+	if len(got) != 1 || got["config_param"] != int64(0) {
+		t.Fatalf("expected only config_param, got %v", got)
+	}
+}
+
+func TestMarshalPayload(t *testing.T) {
+	messages := protoMessages(t)
+	event := testEvent(100)
+	superseded := testEvent(90)
+	corrected := testEvent(90)
+	corrected.Revision = 3
+
+	b := MarshalPayload([]storage.Event{event}, []stream.Correction{{
+		SeqNo:      90,
+		Superseded: []storage.Event{superseded},
+		Events:     []storage.Event{corrected},
+	}})
+	got, err := decode(messages, "Payload", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := got["events"].([]map[string]any)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	checkEvent(t, messages, events[0], event)
+
+	corrections, _ := got["corrections"].([]map[string]any)
+	if len(corrections) != 1 {
+		t.Fatalf("expected 1 correction, got %d", len(corrections))
+	}
+	c := corrections[0]
+	if c["seqno"] != uint64(90) {
+		t.Errorf("Correction.seqno: expected 90, got %v", c["seqno"])
+	}
+	old, _ := c["superseded"].([]map[string]any)
+	if len(old) != 1 {
+		t.Fatalf("expected 1 superseded event, got %d", len(old))
+	}
+	checkEvent(t, messages, old[0], superseded)
+	replaced, _ := c["events"].([]map[string]any)
+	if len(replaced) != 1 {
+		t.Fatalf("expected 1 corrected event, got %d", len(replaced))
+	}
+	checkEvent(t, messages, replaced[0], corrected)
+}
@@ -0,0 +1,200 @@
+// Package fieldcrypt encrypts sensitive values stored in db or config
+// files with AES-GCM. Encrypted values are text prefixed with Prefix,
+// plain values which could be taken for encrypted ones are escaped, so
+// a stored value is never ambiguous even if it comes from chain data.
+//
+// Ciphertext is bound to its scope, e.g. table column, with additional
+// authenticated data, so it can't be moved to another column. Values
+// are encrypted with the current key and decrypted with the current or
+// old keys, which allows key rotation.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Prefix marks encrypted values, the version allows changing the format.
+	Prefix = "enc:v2:"
+	// legacyPrefix marks values encrypted without scope
+	legacyPrefix = "enc:v1:"
+	// escapePrefix marks plain values starting with marker
+	escapePrefix = "enc:plain:"
+	// marker starts every encrypted or escaped value
+	marker = "enc:"
+)
+
+var (
+	ErrNoKey   = errors.New("encryption key is not configured")
+	ErrDecrypt = errors.New("failed to decrypt value")
+)
+
+var current atomic.Pointer[keyring]
+
+// keyring encrypts with the first cipher and decrypts with any of them.
+type keyring struct {
+	ciphers []*Cipher
+}
+
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns cipher with AES-128, AES-192 or AES-256 key.
+func New(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// ParseKey decodes base64 key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key must be 16, 24 or 32 bytes, got %d", len(key))
+}
+
+// Encrypt encrypts plain bound to scope.
+func (c *Cipher) Encrypt(plain, scope string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), []byte(scope))
+
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts value encrypted with scope, values of the legacy
+// format have no scope.
+func (c *Cipher) Decrypt(s, scope string) (string, error) {
+	var aad []byte
+	switch {
+	case strings.HasPrefix(s, Prefix):
+		s, aad = s[len(Prefix):], []byte(scope)
+	case strings.HasPrefix(s, legacyPrefix):
+		s = s[len(legacyPrefix):]
+	default:
+		return "", errors.New("value is not encrypted")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	n := c.aead.NonceSize()
+	if len(data) < n {
+		return "", errors.New("encrypted value is too short")
+	}
+	plain, err := c.aead.Open(nil, data[:n], data[n:], aad)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// SetKey sets key used by Encrypt and old keys which are still accepted
+// by Decrypt, nil key disables encryption.
+func SetKey(key []byte, old ...[]byte) error {
+	if key == nil {
+		current.Store(nil)
+		return nil
+	}
+
+	var ring keyring
+	for _, k := range append([][]byte{key}, old...) {
+		c, err := New(k)
+		if err != nil {
+			return err
+		}
+		ring.ciphers = append(ring.ciphers, c)
+	}
+	current.Store(&ring)
+
+	return nil
+}
+
+// Enabled reports whether key is set.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// IsEncrypted reports whether value is encrypted, escaped plain values
+// aren't.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, Prefix) || IsLegacy(s)
+}
+
+// IsLegacy reports whether value has the format written before scopes
+// and escaping, such value may be a plain one starting with its prefix.
+func IsLegacy(s string) bool {
+	return strings.HasPrefix(s, legacyPrefix)
+}
+
+// Escape returns plain value which is stored without encryption,
+// values starting with the marker of encrypted ones are prefixed.
+func Escape(plain string) string {
+	if strings.HasPrefix(plain, marker) {
+		return escapePrefix + plain
+	}
+
+	return plain
+}
+
+// Encrypt encrypts value bound to scope with the configured key,
+// empty values and values without key are escaped.
+func Encrypt(plain, scope string) (string, error) {
+	ring := current.Load()
+	if ring == nil || plain == "" {
+		return Escape(plain), nil
+	}
+
+	return ring.ciphers[0].Encrypt(plain, scope)
+}
+
+// Decrypt decrypts value encrypted with scope by the current or old
+// keys, plain values are unescaped.
+func Decrypt(s, scope string) (string, error) {
+	if strings.HasPrefix(s, escapePrefix) {
+		return s[len(escapePrefix):], nil
+	}
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	ring := current.Load()
+	if ring == nil {
+		return "", ErrNoKey
+	}
+
+	var err error
+	for _, c := range ring.ciphers {
+		var plain string
+		if plain, err = c.Decrypt(s, scope); err == nil {
+			return plain, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %w", ErrDecrypt, err)
+}
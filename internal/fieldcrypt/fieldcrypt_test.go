@@ -0,0 +1,152 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testScope = "events.comment"
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func setKey(t *testing.T, key []byte, old ...[]byte) {
+	t.Helper()
+
+	if err := SetKey(key, old...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetKey(nil) })
+}
+
+func encrypt(t *testing.T, plain string) string {
+	t.Helper()
+
+	s, err := Encrypt(plain, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	setKey(t, testKey(1))
+
+	for _, plain := range []string{"", "gm", "enc:v2:gm", "enc:plain:gm", strings.Repeat("x", 1000)} {
+		s := encrypt(t, plain)
+		if plain != "" && !IsEncrypted(s) {
+			t.Errorf("%q is stored unencrypted: %q", plain, s)
+		}
+		got, err := Decrypt(s, testScope)
+		if err != nil {
+			t.Fatalf("%q: %s", plain, err)
+		}
+		if got != plain {
+			t.Errorf("expected %q, got %q", plain, got)
+		}
+	}
+}
+
+func TestPrefixCollision(t *testing.T) {
+	forged := Prefix + base64.StdEncoding.EncodeToString(make([]byte, 40))
+	values := []string{
+		forged,
+		legacyPrefix + "gm",
+		escapePrefix + "gm",
+		"enc:",
+		"enc:v3:gm",
+		"gm",
+	}
+
+	for _, withKey := range []bool{false, true} {
+		if withKey {
+			setKey(t, testKey(1))
+		}
+		for _, plain := range values {
+			s := encrypt(t, plain)
+			if !withKey && strings.HasPrefix(plain, marker) && !strings.HasPrefix(s, escapePrefix) {
+				t.Errorf("%q is stored unescaped: %q", plain, s)
+			}
+			got, err := Decrypt(s, testScope)
+			if err != nil {
+				t.Fatalf("%q: %s", plain, err)
+			}
+			if got != plain {
+				t.Errorf("expected %q, got %q", plain, got)
+			}
+		}
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	setKey(t, testKey(1))
+	s := encrypt(t, "gm")
+
+	setKey(t, testKey(2))
+	if _, err := Decrypt(s, testScope); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt, got %v", err)
+	}
+
+	setKey(t, nil)
+	if _, err := Decrypt(s, testScope); !errors.Is(err, ErrNoKey) {
+		t.Errorf("expected ErrNoKey, got %v", err)
+	}
+}
+
+func TestWrongScope(t *testing.T) {
+	setKey(t, testKey(1))
+	s := encrypt(t, "gm")
+
+	if _, err := Decrypt(s, "sink_jobs.payload"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt, got %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	setKey(t, testKey(1))
+	old := encrypt(t, "old")
+
+	setKey(t, testKey(2), testKey(1))
+	fresh := encrypt(t, "fresh")
+	for s, want := range map[string]string{old: "old", fresh: "fresh"} {
+		got, err := Decrypt(s, testScope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+
+	// values encrypted with the new key need only it
+	setKey(t, testKey(2))
+	if _, err := Decrypt(fresh, testScope); err != nil {
+		t.Error(err)
+	}
+	if _, err := Decrypt(old, testScope); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt, got %v", err)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	c, err := New(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := c.aead.Seal(make([]byte, c.aead.NonceSize()), make([]byte, c.aead.NonceSize()), []byte("gm"), nil)
+	legacy := legacyPrefix + base64.StdEncoding.EncodeToString(sealed)
+
+	setKey(t, testKey(1))
+	got, err := Decrypt(legacy, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "gm" {
+		t.Errorf("expected gm, got %q", got)
+	}
+}
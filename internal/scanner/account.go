@@ -0,0 +1,411 @@
+package scanner
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tl"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const backfillPageSize = 16
+
+// SubscribeAccount registers watch of account and schedules backfill of
+// its transactions since from. From is limited by backfill depth, zero
+// from backfills as deep as allowed. Subscribing already watched account
+// returns existing watch, backfill is restarted if it failed or from is
+// earlier than before.
+func (s *Scanner) SubscribeAccount(ctx context.Context, addr *address.Address, from time.Time) (*storage.AccountWatch, error) {
+	if s.backfillDepth > 0 {
+		if earliest := time.Now().Add(-s.backfillDepth).Truncate(time.Second); from.Before(earliest) {
+			from = earliest
+		}
+	}
+	db := app.DB.WithContext(ctx)
+	suppressed, err := storage.IsSuppressed(db, addr)
+	if err != nil {
+		return nil, err
+	}
+	if suppressed {
+		return nil, storage.ErrSuppressed
+	}
+
+	watch := storage.AccountWatch{
+		Address:  rawString(addr),
+		FromTime: from,
+		Status:   storage.WatchPending,
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&watch).Error; err != nil {
+		return nil, err
+	}
+	if err := db.First(&watch, "address = ?", watch.Address).Error; err != nil {
+		return nil, err
+	}
+	if watch.Status == storage.WatchFailed || from.Before(watch.FromTime) {
+		// transactions indexed before are skipped by restarted backfill
+		if from.Before(watch.FromTime) {
+			watch.FromTime = from
+		}
+		err := db.Model(&watch).Updates(map[string]any{
+			"from_time": watch.FromTime,
+			"status":    storage.WatchPending,
+			"error":     "",
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		watch.Status = storage.WatchPending
+		watch.Error = ""
+	}
+
+	select {
+	case s.watches <- struct{}{}:
+	default:
+	}
+
+	return &watch, nil
+}
+
+// AccountWatch returns watch of account, nil if account isn't subscribed.
+func (s *Scanner) AccountWatch(ctx context.Context, addr *address.Address) (*storage.AccountWatch, error) {
+	var watch storage.AccountWatch
+	err := app.DB.WithContext(ctx).First(&watch, "address = ?", rawString(addr)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &watch, nil
+}
+
+// runAccountBackfills processes pending watches on start and after
+// each new subscription. Watches interrupted by restart are resumed,
+// transactions indexed before are skipped.
+func (s *Scanner) runAccountBackfills(ctx context.Context) {
+	for {
+		var watches []storage.AccountWatch
+		err := app.DB.WithContext(ctx).
+			Where("status IN ?", []string{storage.WatchPending, storage.WatchRunning}).
+			Order("created_at").
+			Find(&watches).Error
+		if err != nil {
+			logrus.Errorf("[ACC] failed to load account watches: %s", err)
+		}
+		for _, w := range watches {
+			if ctx.Err() != nil {
+				return
+			}
+			s.backfillAccount(ctx, w)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.watches:
+		}
+	}
+}
+
+func (s *Scanner) backfillAccount(ctx context.Context, watch storage.AccountWatch) {
+	start := time.Now()
+	if err := app.DB.Model(&watch).Update("status", storage.WatchRunning).Error; err != nil {
+		logrus.Errorf("[ACC] failed to start backfill of %s: %s", watch.Address, err)
+		return
+	}
+
+	txs, err := s.backfillTxs(ctx, watch)
+	if err != nil {
+		if ctx.Err() != nil {
+			// resumed on next start
+			return
+		}
+		logrus.Errorf("[ACC] failed to backfill %s: %s", watch.Address, err)
+		err = finishWatch(watch, map[string]any{
+			"status": storage.WatchFailed,
+			"error":  err.Error(),
+		})
+		if err != nil {
+			logrus.Errorf("[ACC] failed to update watch of %s: %s", watch.Address, err)
+		}
+		return
+	}
+
+	err = finishWatch(watch, map[string]any{
+		"status": storage.WatchDone,
+		"txs":    txs,
+	})
+	if err != nil {
+		// still running, resumed on next start
+		logrus.Errorf("[ACC] failed to update watch of %s: %s", watch.Address, err)
+		return
+	}
+	logrus.Infof("[ACC] backfilled [%d] transactions of %s in [%.2fs]",
+		txs, watch.Address, time.Since(start).Seconds())
+}
+
+// finishWatch updates status of backfilled watch unless it was subscribed
+// again with earlier from_time meanwhile, such watch stays pending and is
+// backfilled again.
+func finishWatch(watch storage.AccountWatch, updates map[string]any) error {
+	return app.DB.Model(&storage.AccountWatch{}).
+		Where("address = ? AND from_time = ?", watch.Address, watch.FromTime).
+		Updates(updates).Error
+}
+
+// backfillTxs walks account transactions from the last one back to
+// watch.FromTime and pushes events of transactions from master blocks
+// before the first scanned one. Later blocks are covered by scanner
+// and gap filling.
+func (s *Scanner) backfillTxs(ctx context.Context, watch storage.AccountWatch) (uint32, error) {
+	addr, err := address.ParseRawAddr(watch.Address)
+	if err != nil {
+		return 0, err
+	}
+
+	head, err := s.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	idx, err := s.newAccountIndex(ctx, watch.FromTime)
+	if err != nil {
+		return 0, err
+	}
+
+	acc, err := s.api.GetAccount(ctx, head, addr)
+	if err != nil {
+		return 0, err
+	}
+	if !acc.IsActive || acc.LastTxLT == 0 {
+		return 0, nil
+	}
+
+	err = s.walkAccount(ctx, addr, acc.LastTxLT, acc.LastTxHash, func(page []*tlb.Transaction) (bool, error) {
+		return s.indexPage(ctx, idx, page)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := s.flushIndex(ctx, idx); err != nil {
+		return 0, err
+	}
+
+	return idx.txs, nil
+}
+
+// accountIndex collects events of account transactions by master block.
+type accountIndex struct {
+	from time.Time
+	// transactions of this and later master blocks are left to scanner
+	below   uint32
+	txs     uint32
+	batches map[uint32]*blockBatch
+}
+
+// newAccountIndex creates index of transactions since from in master
+// blocks before the first scanned one, or before the current one if
+// scanning hasn't started. Later blocks are covered by scanner and gap
+// filling, indexing them would store their events twice.
+func (s *Scanner) newAccountIndex(ctx context.Context, from time.Time) (*accountIndex, error) {
+	var first storage.Block
+	if err := app.DB.WithContext(ctx).Order("seq_no").Limit(1).Find(&first).Error; err != nil {
+		return nil, err
+	}
+	below := first.SeqNo
+	if below == 0 {
+		head, err := s.api.CurrentMasterchainInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		below = head.SeqNo
+	}
+
+	return &accountIndex{
+		from:    from,
+		below:   below,
+		batches: make(map[uint32]*blockBatch),
+	}, nil
+}
+
+// walkAccount calls fn for pages of account transactions starting with
+// lt and going back in time, until fn returns false or history ends.
+// Pages go from the newest, but transactions within page are ordered
+// from the oldest, as liteserver returns them.
+func (s *Scanner) walkAccount(
+	ctx context.Context,
+	addr *address.Address,
+	lt uint64,
+	hash []byte,
+	fn func(page []*tlb.Transaction) (bool, error),
+) error {
+	for lt != 0 {
+		page, err := s.listTransactions(ctx, addr, lt, hash)
+		if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		lt, hash = page[0].PrevTxLT, page[0].PrevTxHash
+
+		more, err := fn(page)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexPage decodes events of page transactions not indexed yet, newest
+// first. It returns false once a transaction older than idx.from is met.
+func (s *Scanner) indexPage(ctx context.Context, idx *accountIndex, page []*tlb.Transaction) (bool, error) {
+	indexed, err := indexedTxs(page)
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(page) - 1; i >= 0; i-- {
+		tx := page[i]
+		if int64(tx.Now) < idx.from.Unix() {
+			return false, nil
+		}
+		if _, ok := indexed[hex.EncodeToString(tx.Hash)]; ok {
+			continue
+		}
+		master, err := s.masterByTime(ctx, tx.Now)
+		if err != nil {
+			return false, fmt.Errorf("failed to find master block of tx %x: %w", tx.Hash, err)
+		}
+		if master.SeqNo >= idx.below {
+			continue
+		}
+		events, err := s.processTx(ctx, master, tx, nil)
+		if err != nil {
+			return false, err
+		}
+		idx.txs++
+		if len(events) == 0 {
+			continue
+		}
+		b, ok := idx.batches[master.SeqNo]
+		if !ok {
+			b = &blockBatch{block: storage.Block{SeqNo: master.SeqNo}, backfill: true}
+			idx.batches[master.SeqNo] = b
+		}
+		b.events = append(b.events, events...)
+		b.txs = append(b.txs, hex.EncodeToString(tx.Hash))
+	}
+
+	return true, nil
+}
+
+// flushIndex pushes collected events to writer ordered by master block.
+func (s *Scanner) flushIndex(ctx context.Context, idx *accountIndex) error {
+	seqnos := make([]uint32, 0, len(idx.batches))
+	for seqno := range idx.batches {
+		seqnos = append(seqnos, seqno)
+	}
+	sort.Slice(seqnos, func(i, j int) bool { return seqnos[i] < seqnos[j] })
+	for _, seqno := range seqnos {
+		if err := s.writer.push(ctx, *idx.batches[seqno]); err != nil {
+			return err
+		}
+		delete(idx.batches, seqno)
+	}
+
+	return nil
+}
+
+// listTransactions loads page of account transactions ending with lt,
+// falling back to archive liteservers for old ones.
+func (s *Scanner) listTransactions(ctx context.Context, addr *address.Address, lt uint64, hash []byte) ([]*tlb.Transaction, error) {
+	txs, err := s.api.ListTransactions(ctx, addr, backfillPageSize, lt, hash)
+	if err != nil && s.archive != nil && isNotInDB(err) {
+		txs, err = s.archive.api.ListTransactions(ctx, addr, backfillPageSize, lt, hash)
+	}
+
+	return txs, err
+}
+
+// masterByTime finds master block by generation time. It's the closest
+// block liteserver knows, not necessarily the one which committed the
+// shard block of transaction.
+func (s *Scanner) masterByTime(ctx context.Context, utime uint32) (*ton.BlockIDExt, error) {
+	query := ton.LookupBlock{
+		Mode: 4,
+		ID: &ton.BlockInfoShort{
+			Workchain: address.MasterchainID,
+			Shard:     math.MinInt64,
+		},
+		UTime: utime,
+	}
+
+	var resp tl.Serializable
+	err := s.api.Client().QueryLiteserver(ctx, query, &resp)
+	if err != nil && s.archive != nil && isNotInDB(err) {
+		err = s.archive.api.Client().QueryLiteserver(ctx, query, &resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := resp.(type) {
+	case ton.BlockHeader:
+		return t.ID, nil
+	case ton.LSError:
+		return nil, t
+	}
+
+	return nil, fmt.Errorf("unexpected response %T", resp)
+}
+
+// indexedTxs returns hashes of transactions which already have events,
+// superseded ones included, or were indexed by account history walk.
+func indexedTxs(txs []*tlb.Transaction) (map[string]struct{}, error) {
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, hex.EncodeToString(tx.Hash))
+	}
+
+	var withEvents, walked []string
+	err := app.DB.Unscoped().
+		Model(&storage.Event{}).
+		Distinct("tx_hash").
+		Where("tx_hash IN ?", hashes).
+		Pluck("tx_hash", &withEvents).Error
+	if err != nil {
+		return nil, err
+	}
+	err = app.DB.Model(&storage.IndexedTx{}).
+		Where("tx_hash IN ?", hashes).
+		Pluck("tx_hash", &walked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make(map[string]struct{}, len(withEvents)+len(walked))
+	for _, h := range append(withEvents, walked...) {
+		indexed[h] = struct{}{}
+	}
+
+	return indexed, nil
+}
+
+func rawString(addr *address.Address) string {
+	return fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())
+}
@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// IndexAccountParams limits account history walk, zero values mean no limit.
+type IndexAccountParams struct {
+	// oldest transaction time to index
+	From time.Time
+	// max number of transactions to index
+	Limit uint32
+	// transaction to start from instead of the last one,
+	// to resume interrupted walk
+	LT   uint64
+	Hash []byte
+}
+
+// IndexAccount walks account history backwards independently of block
+// scanning and indexes events of transactions not indexed yet, in master
+// blocks before the first scanned one. Events are stored page by page,
+// so busy accounts don't have to fit in memory.
+// It returns the number of indexed transactions.
+func (s *Scanner) IndexAccount(ctx context.Context, addr *address.Address, p IndexAccountParams) (uint32, error) {
+	lt, hash := p.LT, p.Hash
+	if lt == 0 {
+		head, err := s.api.CurrentMasterchainInfo(ctx)
+		if err != nil {
+			return 0, err
+		}
+		acc, err := s.api.GetAccount(ctx, head, addr)
+		if err != nil {
+			return 0, err
+		}
+		if !acc.IsActive || acc.LastTxLT == 0 {
+			return 0, nil
+		}
+		lt, hash = acc.LastTxLT, acc.LastTxHash
+	}
+
+	start := time.Now()
+	idx, err := s.newAccountIndex(ctx, p.From)
+	if err != nil {
+		return 0, err
+	}
+	err = s.walkAccount(ctx, addr, lt, hash, func(page []*tlb.Transaction) (bool, error) {
+		more, err := s.indexPage(ctx, idx, page)
+		if err != nil {
+			return false, err
+		}
+		if err := s.flushIndex(ctx, idx); err != nil {
+			return false, err
+		}
+
+		oldest := page[0]
+		logrus.Infof("[ACC] indexed [%d] transactions of %s in [%.2fs], resume from lt=%d hash=%x",
+			idx.txs, addr, time.Since(start).Seconds(), oldest.PrevTxLT, oldest.PrevTxHash)
+
+		return more && (p.Limit == 0 || idx.txs < p.Limit), nil
+	})
+
+	return idx.txs, err
+}
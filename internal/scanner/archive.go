@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// archive routes requests for historical blocks, which regular liteservers
+// have already pruned, to dedicated archive liteservers.
+type archive struct {
+	api    *ton.APIClient
+	client *liteclient.ConnectionPool
+	// master blocks with seqno below this value are requested from archive nodes
+	below atomic.Uint32
+}
+
+func newArchive(ctx context.Context, cfgURL string, st *stats) (*archive, error) {
+	client := liteclient.NewConnectionPool()
+	if err := client.AddConnectionsFromConfigUrl(ctx, cfgURL); err != nil {
+		return nil, err
+	}
+
+	return &archive{
+		api:    ton.NewAPIClient(countingClient{LiteClient: client, stats: st}),
+		client: client,
+	}, nil
+}
+
+// isNotInDB reports whether liteserver has no data for requested block.
+func isNotInDB(err error) bool {
+	return errors.Is(err, ton.ErrBlockNotFound) || strings.Contains(err.Error(), "is not in db")
+}
+
+// blockAPI returns api client which should be used for given master block.
+func (s *Scanner) blockAPI(seqno uint32) *ton.APIClient {
+	if s.archive != nil && seqno < s.archive.below.Load() {
+		return s.archive.api
+	}
+
+	return s.api
+}
+
+// markArchival switches requests for master block to archive liteservers
+// if fast liteservers failed to serve it and the block is already behind
+// the head, so it's not just not produced yet.
+func (s *Scanner) markArchival(ctx context.Context, seqno uint32, err error) bool {
+	if s.archive == nil || !isNotInDB(err) || seqno < s.archive.below.Load() {
+		return false
+	}
+
+	lastSeqno, err := s.getLastBlockSeqno(ctx)
+	if err != nil || seqno >= lastSeqno {
+		return false
+	}
+
+	s.archive.below.Store(seqno + 1)
+	logrus.Infof("[SCN] master block %d is not served by liteservers, using archive nodes", seqno)
+
+	return true
+}
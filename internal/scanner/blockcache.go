@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// blockCacheMaxAge is the age of entries removed on start, entries of
+// processed and dead-lettered blocks are removed right away.
+const blockCacheMaxAge = 24 * time.Hour
+
+// blockCache keeps shard info and transaction lists of blocks on disk,
+// so retries of a failed block don't download them from liteservers
+// again. Entries are keyed by BlockIDExt including root hash, so they
+// never become stale. Nil cache passes all requests to liteservers.
+type blockCache struct {
+	dir string
+}
+
+func newBlockCache(dir string) (*blockCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create block cache dir: %w", err)
+	}
+	c := &blockCache{dir: dir}
+	c.prune(blockCacheMaxAge)
+
+	return c, nil
+}
+
+func (c *blockCache) shards(ctx context.Context, api *ton.APIClient, master *ton.BlockIDExt) ([]*ton.BlockIDExt, error) {
+	if c == nil {
+		return api.GetBlockShardsInfo(ctx, master)
+	}
+
+	var shards []*ton.BlockIDExt
+	if c.load("shards", master, &shards) {
+		return shards, nil
+	}
+	shards, err := api.GetBlockShardsInfo(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+	c.store("shards", master, shards)
+
+	return shards, nil
+}
+
+// txs returns short infos of all shard block transactions,
+// only complete lists are cached.
+func (c *blockCache) txs(ctx context.Context, api *ton.APIClient, shard *ton.BlockIDExt) ([]ton.TransactionShortInfo, error) {
+	var txs []ton.TransactionShortInfo
+	if c.load("txs", shard, &txs) {
+		return txs, nil
+	}
+
+	var after *ton.TransactionID3
+	for more := true; more; {
+		page, hasMore, err := api.GetBlockTransactionsV2(ctx, shard, 100, after)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, page...)
+		more = hasMore
+		if more {
+			after = page[len(page)-1].ID3()
+		}
+	}
+	c.store("txs", shard, txs)
+
+	return txs, nil
+}
+
+// drop removes entries of processed or dead-lettered blocks.
+func (c *blockCache) drop(blocks ...*ton.BlockIDExt) {
+	if c == nil {
+		return
+	}
+	for _, b := range blocks {
+		for _, kind := range []string{"shards", "txs"} {
+			if err := os.Remove(c.path(kind, b)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logrus.Warnf("[SCN] failed to remove block cache entry: %s", err)
+			}
+		}
+	}
+}
+
+func (c *blockCache) path(kind string, b *ton.BlockIDExt) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s_%d_%x_%d_%x.json",
+		kind, b.Workchain, uint64(b.Shard), b.SeqNo, b.RootHash))
+}
+
+func (c *blockCache) load(kind string, b *ton.BlockIDExt, dst any) bool {
+	if c == nil {
+		return false
+	}
+	data, err := os.ReadFile(c.path(kind, b))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		logrus.Warnf("[SCN] ignoring broken block cache entry: %s", err)
+		return false
+	}
+
+	return true
+}
+
+// store writes entry through temporary file, so a crash never
+// leaves partial entry behind. Failures only make cache miss.
+func (c *blockCache) store(kind string, b *ton.BlockIDExt, v any) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logrus.Warnf("[SCN] failed to encode block cache entry: %s", err)
+		return
+	}
+	path := c.path(kind, b)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logrus.Warnf("[SCN] failed to write block cache entry: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		logrus.Warnf("[SCN] failed to write block cache entry: %s", err)
+	}
+}
+
+// prune removes entries left by blocks which were never processed.
+func (c *blockCache) prune(maxAge time.Duration) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		logrus.Warnf("[SCN] failed to read block cache dir: %s", err)
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil {
+			logrus.Warnf("[SCN] failed to remove block cache entry: %s", err)
+		}
+	}
+}
+
+func (s *Scanner) dropCached(master *ton.BlockIDExt, shards map[string]*ton.BlockIDExt) {
+	blocks := make([]*ton.BlockIDExt, 0, len(shards)+1)
+	blocks = append(blocks, master)
+	for _, shard := range shards {
+		blocks = append(blocks, shard)
+	}
+	s.blockCache.drop(blocks...)
+}
@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/xssnick/tonutils-go/address"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// CompactionConfig lists extremely active addresses which events are
+// stored as per-block summaries:
+//
+//	{
+//	  "addresses": [
+//	    {"label": "dex_router", "address": "EQ...", "types": ["swap"]}
+//	  ]
+//	}
+//
+// Events with the address as sender or recipient are compacted,
+// empty types match any event type.
+type CompactionConfig struct {
+	Addresses []CompactionRule `json:"addresses"`
+}
+
+type CompactionRule struct {
+	Label   string   `json:"label"`
+	Address string   `json:"address"`
+	Types   []string `json:"types"`
+}
+
+type compactionRule struct {
+	label string
+	addr  *address.Address
+	types map[string]struct{}
+}
+
+// compactor replaces events of configured addresses with summaries.
+type compactor struct {
+	rules []compactionRule
+}
+
+func loadCompactor(path string) (*compactor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg CompactionConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse compaction config: %w", err)
+	}
+
+	c := &compactor{}
+	for i, r := range cfg.Addresses {
+		if r.Label == "" {
+			return nil, fmt.Errorf("compaction rule %d: label is required", i)
+		}
+		addr, err := address.ParseAddr(r.Address)
+		if err != nil {
+			addr, err = address.ParseRawAddr(r.Address)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("compaction rule %d: invalid address %q", i, r.Address)
+		}
+		rule := compactionRule{label: r.Label, addr: addr}
+		if len(r.Types) > 0 {
+			rule.types = make(map[string]struct{}, len(r.Types))
+			for _, t := range r.Types {
+				if !storage.IsEventType(t) {
+					return nil, fmt.Errorf("compaction rule %d: unknown event type %q", i, t)
+				}
+				rule.types[t] = struct{}{}
+			}
+		}
+		c.rules = append(c.rules, rule)
+	}
+
+	return c, nil
+}
+
+// CheckCompactionConfig validates compaction config file.
+func CheckCompactionConfig(path string) error {
+	_, err := loadCompactor(path)
+	return err
+}
+
+// compact returns events which should be stored individually
+// and summaries of compacted ones.
+func (c *compactor) compact(events []storage.Event) ([]storage.Event, []storage.EventSummary) {
+	type key struct {
+		seqno uint32
+		label string
+		typ   string
+	}
+	var (
+		kept      = events[:0]
+		summaries []storage.EventSummary
+		index     = make(map[key]int)
+	)
+	for _, e := range events {
+		rule := c.match(e)
+		if rule == nil {
+			kept = append(kept, e)
+			continue
+		}
+
+		k := key{seqno: e.SeqNo, label: rule.label, typ: e.Type}
+		i, ok := index[k]
+		if !ok {
+			i = len(summaries)
+			index[k] = i
+			summaries = append(summaries, storage.EventSummary{
+				SeqNo:   e.SeqNo,
+				Label:   rule.label,
+				Address: rule.addr.String(),
+				Type:    e.Type,
+				Amount:  "0",
+				FirstLT: e.LT,
+			})
+		}
+		s := &summaries[i]
+		s.Count++
+		s.FirstLT = min(s.FirstLT, e.LT)
+		s.LastLT = max(s.LastLT, e.LT)
+		if amount, ok := new(big.Int).SetString(e.Amount, 10); ok {
+			sum, _ := new(big.Int).SetString(s.Amount, 10)
+			s.Amount = sum.Add(sum, amount).String()
+		}
+	}
+
+	return kept, summaries
+}
+
+func (c *compactor) match(e storage.Event) *compactionRule {
+	for i := range c.rules {
+		r := &c.rules[i]
+		if r.types != nil {
+			if _, ok := r.types[e.Type]; !ok {
+				continue
+			}
+		}
+		if sameAddr(r.addr, e.Sender) || sameAddr(r.addr, e.Recipient) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func sameAddr(addr *address.Address, s string) bool {
+	if s == "" {
+		return false
+	}
+	other, err := address.ParseAddr(s)
+	if err != nil {
+		return false
+	}
+
+	return addr.Equals(other)
+}
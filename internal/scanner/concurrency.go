@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	concurrencyMin     = 1
+	concurrencyInitial = 8
+	// window error rate above which concurrency is halved
+	concurrencyMaxErrRate = 0.05
+	// window latency above baseline times this factor halves concurrency
+	concurrencyLatencyFactor = 2
+)
+
+// ConcurrencyStats is a current state of adaptive worker pool.
+type ConcurrencyStats struct {
+	Name     string        `json:"name"`
+	Limit    int           `json:"limit"`
+	InFlight int           `json:"in_flight"`
+	Baseline time.Duration `json:"baseline_latency_ns"`
+}
+
+// aimdLimiter bounds number of concurrent calls and tunes the bound
+// from observed latency and errors: it's increased by one after each
+// window of calls which saturated the pool and halved after window
+// with errors or latency well above the baseline. Window is as long
+// as the current limit, so the limit reacts once per round of calls.
+type aimdLimiter struct {
+	name string
+	max  int
+
+	mu sync.Mutex
+	// closed and replaced on every release to wake waiting acquires
+	released chan struct{}
+	limit    int
+	inFlight int
+	// the lowest window latency, slowly following the current one,
+	// so it adapts after liteservers change
+	baseline time.Duration
+
+	calls     int
+	errs      int
+	latency   time.Duration
+	saturated bool
+}
+
+func newAIMDLimiter(name string, maxLimit int) *aimdLimiter {
+	return &aimdLimiter{
+		name:     name,
+		max:      maxLimit,
+		released: make(chan struct{}),
+		limit:    min(concurrencyInitial, maxLimit),
+	}
+}
+
+// acquire waits for free slot until ctx is done, every successful acquire
+// must be followed by release.
+func (l *aimdLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	for l.inFlight >= l.limit {
+		l.saturated = true
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.inFlight++
+	l.mu.Unlock()
+
+	return nil
+}
+
+// release frees slot and records outcome of the call, benign errors
+// should be passed as nil.
+func (l *aimdLimiter) release(d time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.calls++
+	l.latency += d
+	if err != nil {
+		l.errs++
+	}
+	if l.calls >= l.limit {
+		l.adjust()
+	}
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// limiterError drops errors of blocks and transactions liteserver doesn't
+// have, they don't mean it's overloaded.
+func limiterError(err error) error {
+	if err != nil && isNotInDB(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (l *aimdLimiter) adjust() {
+	avg := l.latency / time.Duration(l.calls)
+	errRate := float64(l.errs) / float64(l.calls)
+
+	prev := l.limit
+	switch {
+	case errRate > concurrencyMaxErrRate || (l.baseline > 0 && avg > l.baseline*concurrencyLatencyFactor):
+		l.limit = max(concurrencyMin, l.limit/2)
+	case l.saturated && l.limit < l.max:
+		l.limit++
+	}
+	if l.baseline == 0 || avg < l.baseline {
+		l.baseline = avg
+	} else {
+		l.baseline += (avg - l.baseline) / 32
+	}
+	if l.limit != prev {
+		logrus.Debugf("[CNC] %s concurrency %d -> %d, latency %s, baseline %s, errors %.2f",
+			l.name, prev, l.limit, avg, l.baseline, errRate)
+	}
+
+	l.calls, l.errs, l.latency, l.saturated = 0, 0, 0, false
+}
+
+func (l *aimdLimiter) stats() ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return ConcurrencyStats{
+		Name:     l.name,
+		Limit:    l.limit,
+		InFlight: l.inFlight,
+		Baseline: l.baseline,
+	}
+}
+
+// Concurrency returns state of adaptive worker pools.
+func (s *Scanner) Concurrency() []ConcurrencyStats {
+	return []ConcurrencyStats{s.fetchLimiter.stats(), s.parseLimiter.stats()}
+}
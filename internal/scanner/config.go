@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"sync"
+
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// configMonitor detects changes of blockchain config params
+// between consecutive master blocks.
+type configMonitor struct {
+	params []int32
+
+	// params of the latest checked master block
+	mu     sync.Mutex
+	seqno  uint32
+	values map[int32]*cell.Cell
+}
+
+func newConfigMonitor(params []int32) *configMonitor {
+	return &configMonitor{params: params}
+}
+
+// configEvents returns config_changed events for params which differ
+// from the previous master block.
+func (s *Scanner) configEvents(ctx context.Context, api *ton.APIClient, master *ton.BlockIDExt) ([]storage.Event, error) {
+	m := s.config
+	if m == nil {
+		return nil, nil
+	}
+
+	cur, err := api.GetBlockchainConfig(ctx, master, m.params...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	prev, prevSeqno := m.values, m.seqno
+	if master.SeqNo > m.seqno {
+		m.seqno, m.values = master.SeqNo, cur.All()
+	}
+	m.mu.Unlock()
+
+	// blocks are not always checked in order, e.g. on reparse
+	if prev == nil || prevSeqno != master.SeqNo-1 {
+		prevMaster, err := s.lookupMaster(ctx, master.SeqNo-1)
+		if err != nil {
+			return nil, err
+		}
+		prevCfg, err := s.blockAPI(prevMaster.SeqNo).GetBlockchainConfig(ctx, prevMaster, m.params...)
+		if err != nil {
+			return nil, err
+		}
+		prev = prevCfg.All()
+	}
+
+	var events []storage.Event
+	for _, param := range m.params {
+		value := cur.Get(param)
+		if !configChanged(prev[param], value) {
+			continue
+		}
+		// removed param has empty value
+		var data string
+		if value != nil {
+			data = base64.StdEncoding.EncodeToString(value.ToBOC())
+		}
+		events = append(events, storage.Event{
+			Type:        storage.EventTypeConfigChanged,
+			SeqNo:       master.SeqNo,
+			Amount:      "0",
+			ConfigParam: &param,
+			ConfigValue: data,
+			Success:     true,
+		})
+	}
+
+	return events, nil
+}
+
+// configChanged compares param values, param missing in one of the
+// configs is a change.
+func configChanged(prev, cur *cell.Cell) bool {
+	if prev == nil || cur == nil {
+		return prev != cur
+	}
+
+	return !bytes.Equal(prev.Hash(), cur.Hash())
+}
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const relayBatchSize = 100
+
+// relayCorrections publishes corrections stored by standalone tools to
+// subscribers of this scanner. Correction is deleted after it's
+// published, so like other corrections it reaches only subscribers
+// connected at the time, backfill doesn't replay it.
+func (s *Scanner) relayCorrections(ctx context.Context) error {
+	if s.writer.broker == nil {
+		return nil
+	}
+
+	for {
+		var pending []storage.PendingCorrection
+		err := app.DB.WithContext(ctx).Order("id").Limit(relayBatchSize).Find(&pending).Error
+		if err != nil {
+			return err
+		}
+
+		for _, p := range pending {
+			c := stream.Correction{SeqNo: p.SeqNo}
+			if len(p.EventIDs) > 0 {
+				if err := app.DB.WithContext(ctx).Order("event_index").Find(&c.Events, p.EventIDs).Error; err != nil {
+					return err
+				}
+			}
+			if len(p.SupersededIDs) > 0 {
+				err := app.DB.WithContext(ctx).Unscoped().Order("event_index").Find(&c.Superseded, p.SupersededIDs).Error
+				if err != nil {
+					return err
+				}
+			}
+			s.writer.broker.Publish(stream.Batch{Corrections: []stream.Correction{c}})
+
+			if err := app.DB.WithContext(ctx).Delete(&p).Error; err != nil {
+				return err
+			}
+			logrus.Debugf("[SCN] relayed correction of block [%d]", p.SeqNo)
+		}
+
+		if len(pending) < relayBatchSize {
+			return nil
+		}
+	}
+}
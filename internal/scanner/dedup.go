@@ -0,0 +1,171 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+)
+
+type dedupKey struct {
+	jettonMaster string
+	recipient    string
+	sender       string
+	amount       string
+	comment      string
+}
+
+type dedupSeen struct {
+	seqno  uint32
+	lt     uint64
+	utime  uint32
+	txHash string
+}
+
+// notifyDedup suppresses jetton notifications identical to one seen less
+// than window ago, some contracts repeat notifications and naive payment
+// consumers would credit them twice. Notifications are identical if they
+// have the same sender, amount and comment and are sent to the same
+// recipient of the same jetton. Time is taken from transactions.
+//
+// Writer filters events sorted by (block, lt) against earlier ones of the
+// same flush and stored ones, so the result doesn't depend on restarts
+// and reparses give the same result as the first parse. Blocks stored out
+// of order by gap filling don't suppress notifications stored after them.
+type notifyDedup struct {
+	window uint32
+}
+
+func newNotifyDedup(window time.Duration) *notifyDedup {
+	return &notifyDedup{window: uint32(window.Seconds())}
+}
+
+// filter drops duplicate notifications from events sorted by (block, lt)
+// and counts them in stats. Stored events of reparsed blocks are being
+// superseded, they don't suppress anything.
+func (d *notifyDedup) filter(
+	db *gorm.DB,
+	events []storage.Event,
+	reparsed map[uint32]struct{},
+	st *stats,
+) ([]storage.Event, error) {
+	seen, err := d.stored(db, events, reparsed)
+	if err != nil {
+		return nil, err
+	}
+	if seen == nil {
+		return events, nil
+	}
+
+	kept := events[:0]
+	for _, e := range events {
+		if !isNotification(e) {
+			kept = append(kept, e)
+			continue
+		}
+
+		key := notifyKey(e)
+		if prev, ok := d.duplicateOf(seen[key], e); ok {
+			logrus.Infof("[DDP] suppressed notification in tx %s, duplicate of tx %s", e.TxHash, prev.txHash)
+			st.suppressed.Add(1)
+			continue
+		}
+		seen[key] = append(seen[key], dedupSeen{seqno: e.SeqNo, lt: e.LT, utime: e.UTime, txHash: e.TxHash})
+		kept = append(kept, e)
+	}
+
+	return kept, nil
+}
+
+// stored loads notifications which can suppress ones of events, nil if
+// events have no notifications. Master blocks are at least a second
+// apart, so window in seconds bounds the range of blocks to look at.
+func (d *notifyDedup) stored(
+	db *gorm.DB,
+	events []storage.Event,
+	reparsed map[uint32]struct{},
+) (map[dedupKey][]dedupSeen, error) {
+	var (
+		found              bool
+		minSeqno, maxSeqno uint32
+		minUTime, maxUTime uint32
+		recipients         = make(map[string]struct{})
+	)
+	for _, e := range events {
+		if !isNotification(e) {
+			continue
+		}
+		if !found {
+			minSeqno, maxSeqno, minUTime, maxUTime = e.SeqNo, e.SeqNo, e.UTime, e.UTime
+			found = true
+		}
+		minSeqno, maxSeqno = min(minSeqno, e.SeqNo), max(maxSeqno, e.SeqNo)
+		minUTime, maxUTime = min(minUTime, e.UTime), max(maxUTime, e.UTime)
+		recipients[e.Recipient] = struct{}{}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	list := make([]string, 0, len(recipients))
+	for r := range recipients {
+		list = append(list, r)
+	}
+	var stored []storage.Event
+	err := db.
+		Where("type = ? AND opcode = ?", storage.EventTypeJettonTransfer, structures.OpJettonNotify).
+		Where("seq_no BETWEEN ? AND ?", minSeqno-min(minSeqno, d.window), maxSeqno).
+		Where("utime BETWEEN ? AND ?", minUTime-min(minUTime, d.window), maxUTime+d.window).
+		Where("recipient IN ?", list).
+		Find(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[dedupKey][]dedupSeen)
+	for _, e := range stored {
+		if _, ok := reparsed[e.SeqNo]; ok {
+			continue
+		}
+		key := notifyKey(e)
+		seen[key] = append(seen[key], dedupSeen{seqno: e.SeqNo, lt: e.LT, utime: e.UTime, txHash: e.TxHash})
+	}
+
+	return seen, nil
+}
+
+// duplicateOf returns earlier notification of other tx within window.
+func (d *notifyDedup) duplicateOf(seen []dedupSeen, e storage.Event) (dedupSeen, bool) {
+	for _, prev := range seen {
+		earlier := prev.seqno < e.SeqNo || prev.seqno == e.SeqNo && prev.lt < e.LT
+		if earlier && prev.txHash != e.TxHash && absDiff(prev.utime, e.UTime) < d.window {
+			return prev, true
+		}
+	}
+
+	return dedupSeen{}, false
+}
+
+func isNotification(e storage.Event) bool {
+	return e.Type == storage.EventTypeJettonTransfer && e.Opcode == structures.OpJettonNotify
+}
+
+func notifyKey(e storage.Event) dedupKey {
+	return dedupKey{
+		jettonMaster: e.JettonMaster,
+		recipient:    e.Recipient,
+		sender:       e.Sender,
+		amount:       e.Amount,
+		comment:      string(e.Comment),
+	}
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
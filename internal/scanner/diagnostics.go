@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockProgress tracks processing of master block for diagnostics.
+type blockProgress struct {
+	start        time.Time
+	shards       atomic.Int64
+	shardsLoaded atomic.Int64
+	txs          atomic.Int64
+	txsProcessed atomic.Int64
+}
+
+// dumpDiagnostics writes progress of blocks in flight, liteserver queries
+// waiting for response and stacks of all goroutines to a file, to find
+// out where processing of block got stuck.
+func (s *Scanner) dumpDiagnostics(seqno uint32) {
+	name := filepath.Join(s.diagnosticsDir,
+		fmt.Sprintf("block-%d-%s.txt", seqno, time.Now().UTC().Format("20060102T150405")))
+	f, err := os.Create(name)
+	if err != nil {
+		logrus.Errorf("[SCN] failed to create diagnostics file: %s", err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	s.writeDiagnostics(w, seqno)
+	if err := w.Flush(); err != nil {
+		logrus.Errorf("[SCN] failed to write diagnostics: %s", err)
+		return
+	}
+
+	logrus.Errorf("[SCN] block [%d] is processed longer than %s, diagnostics dumped to %s",
+		seqno, s.blockTimeout, name)
+}
+
+func (s *Scanner) writeDiagnostics(w *bufio.Writer, seqno uint32) {
+	now := time.Now()
+	fmt.Fprintf(w, "block %d timed out after %s at %s\n", seqno, s.blockTimeout, now.UTC().Format(time.RFC3339))
+
+	fmt.Fprintln(w, "\nblocks in flight:")
+	s.inFlight.Range(func(key, value any) bool {
+		p := value.(*blockProgress)
+		fmt.Fprintf(w, "  %d: %s, shards loaded %d/%d, txs processed %d/%d\n",
+			key.(uint32),
+			now.Sub(p.start).Round(time.Millisecond),
+			p.shardsLoaded.Load(), p.shards.Load(),
+			p.txsProcessed.Load(), p.txs.Load(),
+		)
+		return true
+	})
+
+	fmt.Fprintln(w, "\nlast seen shard seqnos:")
+	s.shardsMu.Lock()
+	shards := make([]string, 0, len(s.lastShardsSeqNo))
+	for id, shardSeqno := range s.lastShardsSeqNo {
+		shards = append(shards, fmt.Sprintf("  %s: %d", id, shardSeqno))
+	}
+	s.shardsMu.Unlock()
+	sort.Strings(shards)
+	for _, line := range shards {
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintln(w, "\nliteserver queries in flight:")
+	var calls []liteCall
+	s.stats.calls.Range(func(_, value any) bool {
+		calls = append(calls, value.(liteCall))
+		return true
+	})
+	sort.Slice(calls, func(i, j int) bool { return calls[i].start.Before(calls[j].start) })
+	for _, c := range calls {
+		fmt.Fprintf(w, "  %s: %s\n", c.query, now.Sub(c.start).Round(time.Millisecond))
+	}
+
+	fmt.Fprintln(w, "\ngoroutines:")
+	if err := pprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		fmt.Fprintf(w, "failed to dump goroutines: %s\n", err)
+	}
+}
@@ -0,0 +1,280 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/adnl"
+	"github.com/xssnick/tonutils-go/adnl/dht"
+	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/tl"
+)
+
+const (
+	discoveryInterval   = 30 * time.Second
+	discoveryTimeout    = 10 * time.Second
+	reconnectTries      = 3
+	reconnectDelay      = 3 * time.Second
+	connectTimeout      = 7 * time.Second
+	defaultMinAliveConn = 2
+)
+
+// discovery keeps track of alive liteserver connections and, when the pool
+// is degraded, resolves current addresses of known liteserver keys through
+// the TON DHT and connects to them. Liteservers of config are usually not
+// in the DHT, so they are reconnected on every tick until they are back.
+type discovery struct {
+	pool     *liteclient.ConnectionPool
+	dht      *dht.Client
+	keys     []string
+	minAlive int
+	static   map[string]string // key -> address of config liteservers
+
+	mu           sync.Mutex
+	alive        map[string]string // key -> address
+	reconnecting map[string]struct{}
+}
+
+func newDiscovery(
+	pool *liteclient.ConnectionPool,
+	netCfg *liteclient.GlobalConfig,
+	extraKeys []string,
+	minAlive int,
+) (*discovery, error) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	gateway := adnl.NewGateway(priv)
+	if err := gateway.StartClient(); err != nil {
+		return nil, fmt.Errorf("failed to start adnl gateway: %w", err)
+	}
+
+	dhtClient, err := dht.NewClientFromConfig(gateway, netCfg)
+	if err != nil {
+		_ = gateway.Close()
+		return nil, fmt.Errorf("failed to init dht client: %w", err)
+	}
+
+	if minAlive <= 0 {
+		minAlive = defaultMinAliveConn
+	}
+
+	d := &discovery{
+		pool:         pool,
+		dht:          dhtClient,
+		minAlive:     minAlive,
+		static:       make(map[string]string),
+		alive:        make(map[string]string),
+		reconnecting: make(map[string]struct{}),
+	}
+	for _, ls := range netCfg.Liteservers {
+		d.keys = append(d.keys, ls.ID.Key)
+		d.static[ls.ID.Key] = fmt.Sprintf("%s:%d", ipToString(ls.IP), ls.Port)
+	}
+	d.keys = append(d.keys, extraKeys...)
+
+	pool.SetOnDisconnect(d.onDisconnect)
+
+	return d, nil
+}
+
+// connect adds connections to liteservers from config,
+// it returns on the first successful connection like liteclient does.
+func (d *discovery) connect(ctx context.Context, netCfg *liteclient.GlobalConfig) error {
+	if len(netCfg.Liteservers) == 0 {
+		return liteclient.ErrNoConnections
+	}
+
+	var (
+		wg     sync.WaitGroup
+		result = make(chan error, len(netCfg.Liteservers))
+	)
+	for _, ls := range netCfg.Liteservers {
+		addr := fmt.Sprintf("%s:%d", ipToString(ls.IP), ls.Port)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result <- d.addConnection(ctx, addr, ls.ID.Key)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(result)
+	}()
+
+	var err error
+	for err = range result {
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (d *discovery) addConnection(ctx context.Context, addr, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	if err := d.pool.AddConnection(ctx, addr, key); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.alive[key] = addr
+	d.mu.Unlock()
+
+	return nil
+}
+
+// onDisconnect retries connection a few times, then liteserver is left
+// to run, which reconnects config liteservers and discovers others.
+func (d *discovery) onDisconnect(addr, key string) {
+	d.mu.Lock()
+	delete(d.alive, key)
+	d.reconnecting[key] = struct{}{}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.reconnecting, key)
+		d.mu.Unlock()
+	}()
+
+	for i := 0; i < reconnectTries; i++ {
+		if err := d.addConnection(context.Background(), addr, key); err == nil {
+			return
+		}
+		time.Sleep(reconnectDelay)
+	}
+
+	logrus.Warnf("[DSC] liteserver %s is down", addr)
+}
+
+func (d *discovery) aliveCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.alive)
+}
+
+// isAlive reports whether liteserver is connected or being reconnected.
+func (d *discovery) isAlive(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.alive[key]
+	_, reconnecting := d.reconnecting[key]
+	return ok || reconnecting
+}
+
+func (d *discovery) run(ctx context.Context) {
+	ticker := time.NewTicker(discoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n := d.reconnectStatic(ctx); n > 0 {
+			logrus.Infof("[DSC] reconnected to [%d] liteservers of config", n)
+		}
+
+		alive := d.aliveCount()
+		if alive >= d.minAlive {
+			continue
+		}
+
+		logrus.Warnf("[DSC] only [%d] liteservers alive, looking up more in DHT", alive)
+		found := d.discover(ctx)
+		logrus.Infof("[DSC] connected to [%d] liteservers found in DHT", found)
+	}
+}
+
+// reconnectStatic connects to config liteservers which are down and
+// returns number of new connections.
+func (d *discovery) reconnectStatic(ctx context.Context) int {
+	n := 0
+	for key, addr := range d.static {
+		if d.isAlive(key) {
+			continue
+		}
+		if err := d.addConnection(ctx, addr, key); err != nil {
+			logrus.Debugf("[DSC] failed to reconnect to %s: %s", addr, err)
+			continue
+		}
+		n++
+	}
+
+	return n
+}
+
+// discover resolves addresses of liteservers which are not connected
+// and returns number of new connections.
+func (d *discovery) discover(ctx context.Context) int {
+	found := 0
+	for _, key := range d.keys {
+		if d.isAlive(key) {
+			continue
+		}
+
+		addrs, err := d.resolve(ctx, key)
+		if err != nil {
+			logrus.Debugf("[DSC] failed to resolve liteserver %s: %s", key, err)
+			continue
+		}
+
+		for _, addr := range addrs {
+			if err := d.addConnection(ctx, addr, key); err != nil {
+				logrus.Debugf("[DSC] failed to connect to %s: %s", addr, err)
+				continue
+			}
+			found++
+			break
+		}
+	}
+
+	return found
+}
+
+func (d *discovery) resolve(ctx context.Context, key string) ([]string, error) {
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	id, err := tl.Hash(adnl.PublicKeyED25519{Key: pub})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	list, _, err := d.dht.FindAddresses(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(list.Addresses))
+	for _, udp := range list.Addresses {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", udp.IP.String(), udp.Port))
+	}
+
+	return addrs, nil
+}
+
+func (d *discovery) stop() {
+	d.dht.Close()
+}
+
+func ipToString(ip int64) string {
+	return fmt.Sprintf("%d.%d.%d.%d", (ip>>24)&0xff, (ip>>16)&0xff, (ip>>8)&0xff, ip&0xff)
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// crossCheck emulates transaction if emulator is set and returns
+// suspicious event when emulated outcome differs from observed one.
+func (s *Scanner) crossCheck(ctx context.Context, tx *handler.Tx) *storage.Event {
+	emulator := s.handlers.Emulator()
+	if emulator == nil {
+		return nil
+	}
+
+	emulated, err := emulator.Emulate(ctx, tx)
+	if err != nil {
+		logrus.Warnf("[EMU] failed to emulate tx %x: %s", tx.Tx.Hash, err)
+		return nil
+	}
+	observed := handler.ObservedOutcome(tx.Tx)
+	if *emulated == observed {
+		return nil
+	}
+
+	logrus.Warnf("[EMU] tx %x outcome differs from emulated: %+v != %+v", tx.Tx.Hash, observed, *emulated)
+	comment := fmt.Sprintf("emulated success=%t exit_code=%d out_msgs=%d, observed success=%t exit_code=%d out_msgs=%d",
+		emulated.Success, emulated.ExitCode, emulated.OutMsgs,
+		observed.Success, observed.ExitCode, observed.OutMsgs)
+
+	return &storage.Event{
+		Type:      storage.EventTypeSuspicious,
+		SeqNo:     tx.Master.SeqNo,
+		LT:        tx.Tx.LT,
+		TxHash:    hex.EncodeToString(tx.Tx.Hash),
+		Opcode:    tx.Opcode,
+		Sender:    tx.Msg.SrcAddr.String(),
+		Recipient: tx.Msg.DstAddr.String(),
+		Amount:    "0",
+		Comment:   storage.EncryptedString(comment),
+		Success:   observed.Success,
+	}
+}
@@ -0,0 +1,29 @@
+package scanner
+
+import (
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// recordUnresolved stores exotic cell which prevented transaction decoding,
+// so the block can be reparsed later.
+func recordUnresolved(seqno uint32, tx *tlb.Transaction, handlerName string, cellErr *handler.UnresolvedCellError) {
+	logrus.Warnf("[SCN] tx %x in block %d skipped: %s", tx.Hash, seqno, cellErr)
+
+	c := storage.UnresolvedCell{
+		SeqNo:    seqno,
+		TxHash:   hex.EncodeToString(tx.Hash),
+		Handler:  handlerName,
+		CellType: handler.CellTypeName(cellErr.Type),
+		CellHash: hex.EncodeToString(cellErr.Hash),
+	}
+	if err := app.DB.Create(&c).Error; err != nil {
+		logrus.Errorf("[SCN] failed to record unresolved cell: %s", err)
+	}
+}
@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// ErrUnknownHandler is returned by Rerun for handler names not registered.
+var ErrUnknownHandler = errors.New("unknown handler")
+
+// handlerFilter selects handlers to run, see Rerun.
+type handlerFilter func(name string) bool
+
+// handlerError is an error of critical handler which fails the block.
+type handlerError struct {
+	handler string
+	err     error
+}
+
+func (e *handlerError) Error() string {
+	return fmt.Sprintf("handler %s: %s", e.handler, e.err)
+}
+
+func (e *handlerError) Unwrap() error {
+	return e.err
+}
+
+// recordFailed adds block to dead-letter table, failures are only
+// logged because the block is retried by gap filling anyway.
+func recordFailed(seqno uint32, err error) {
+	var name string
+	var hErr *handlerError
+	if errors.As(err, &hErr) {
+		name = hErr.handler
+	}
+
+	if err := storage.AddFailedBlock(app.DB, seqno, name, err); err != nil {
+		logrus.Errorf("[SCN] failed to record failed block %d: %s", seqno, err)
+	}
+}
+
+// RerunParams select handlers for rerun of failed block. If Handlers
+// is set only they are run, Disabled handlers are never run. Unknown
+// handler names are rejected.
+type RerunParams struct {
+	Handlers []string `json:"handlers,omitempty"`
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+func (p RerunParams) filter() handlerFilter {
+	if len(p.Handlers) == 0 && len(p.Disabled) == 0 {
+		return nil
+	}
+
+	only := make(map[string]struct{}, len(p.Handlers))
+	for _, name := range p.Handlers {
+		only[name] = struct{}{}
+	}
+	disabled := make(map[string]struct{}, len(p.Disabled))
+	for _, name := range p.Disabled {
+		disabled[name] = struct{}{}
+	}
+
+	return func(name string) bool {
+		if _, ok := disabled[name]; ok {
+			return false
+		}
+		if len(only) == 0 {
+			return true
+		}
+		_, ok := only[name]
+		return ok
+	}
+}
+
+// validate checks that params name only registered handlers, typo in
+// Disabled would otherwise run the broken handler again.
+func (p RerunParams) validate(registered []string) error {
+	known := make(map[string]struct{}, len(registered))
+	for _, name := range registered {
+		known[name] = struct{}{}
+	}
+	for _, name := range append(append([]string(nil), p.Handlers...), p.Disabled...) {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("%w %q", ErrUnknownHandler, name)
+		}
+	}
+
+	return nil
+}
+
+func (p RerunParams) String() string {
+	var parts []string
+	if len(p.Handlers) > 0 {
+		parts = append(parts, "only "+strings.Join(p.Handlers, ","))
+	}
+	if len(p.Disabled) > 0 {
+		parts = append(parts, "without "+strings.Join(p.Disabled, ","))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// Rerun processes failed block again with chosen handlers, so one
+// broken decoder doesn't hold back other events of the block. The
+// block is stored with events of the handlers that were run and the
+// dead-letter entry is resolved once it's committed. Failed rerun
+// updates the entry.
+func (s *Scanner) Rerun(ctx context.Context, seqno uint32, p RerunParams) error {
+	if err := p.validate(s.handlers.Names()); err != nil {
+		return err
+	}
+
+	var failed storage.FailedBlock
+	if err := app.DB.WithContext(ctx).First(&failed, "seq_no = ?", seqno).Error; err != nil {
+		return err
+	}
+
+	if err := s.reparse(ctx, seqno, p.filter()); err != nil {
+		return err
+	}
+
+	if err := resolveFailed(ctx, seqno, p.String()); err != nil {
+		return fmt.Errorf("block is rerun, but failed to resolve it: %w", err)
+	}
+	logrus.Infof("[SCN] failed block [%d] rerun %s", seqno, p)
+
+	return nil
+}
+
+// resolveFailed marks dead-letter entry of block as resolved, if any.
+func resolveFailed(ctx context.Context, seqno uint32, rerunHandlers string) error {
+	return app.DB.WithContext(ctx).
+		Model(&storage.FailedBlock{}).
+		Where("seq_no = ? AND resolved_at IS NULL", seqno).
+		Updates(map[string]any{
+			"rerun_handlers": rerunHandlers,
+			"resolved_at":    time.Now(),
+		}).Error
+}
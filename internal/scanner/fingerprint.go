@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton"
+	"gorm.io/gorm/clause"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// jettonWallet returns code hash and implementation type of jetton wallet.
+// Types are detected by get methods specific to implementations and
+// cached by code hash, new codes are stored in db.
+func (s *Scanner) jettonWallet(ctx context.Context, master *ton.BlockIDExt, wallet *address.Address) (string, string, error) {
+	hash, err := s.codeHash(ctx, master, wallet)
+	if err != nil {
+		return "", "", err
+	}
+	if hash == nil {
+		return "", "", nil
+	}
+	codeHash := hex.EncodeToString(hash)
+	if typ, ok := s.walletTypes.Load(codeHash); ok {
+		return codeHash, typ, nil
+	}
+
+	typ, err := s.classifyJettonWallet(ctx, master, wallet)
+	if err != nil {
+		return codeHash, "", err
+	}
+	s.walletTypes.Store(codeHash, typ)
+
+	code := storage.JettonWalletCode{
+		CodeHash:   codeHash,
+		Type:       typ,
+		FirstSeqNo: master.SeqNo,
+		Wallet:     wallet.String(),
+	}
+	if err := app.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&code).Error; err != nil {
+		logrus.Errorf("[JTN] failed to store wallet code %s: %s", codeHash, err)
+	}
+	logrus.Infof("[JTN] new jetton wallet code %s: %s", codeHash, typ)
+
+	return codeHash, typ, nil
+}
+
+func (s *Scanner) classifyJettonWallet(ctx context.Context, master *ton.BlockIDExt, wallet *address.Address) (string, error) {
+	for _, c := range []struct {
+		method string
+		typ    string
+	}{
+		{method: "is_claimed", typ: storage.JettonWalletMintless},
+		{method: "get_status", typ: storage.JettonWalletGoverned},
+	} {
+		_, err := s.blockAPI(master.SeqNo).RunGetMethod(ctx, master, wallet, c.method)
+		if err == nil {
+			return c.typ, nil
+		}
+		// method is not implemented
+		var execErr ton.ContractExecError
+		if !errors.As(err, &execErr) {
+			return "", err
+		}
+	}
+
+	return storage.JettonWalletStandard, nil
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	gapsPerCheck   = 100
+	blocksPerCheck = 1000
+)
+
+// fillGaps looks for recent master blocks missing in db, skipped after
+// processing errors or lost on crashes, and processes them again.
+// Filled blocks are published as corrections with no superseded events,
+// never as live events: they are behind resume tokens consumers already
+// have, so live events of them would be skipped as delivered.
+func (s *Scanner) fillGaps(ctx context.Context) error {
+	gaps, err := storage.FindGaps(app.DB, gapsPerCheck)
+	if err != nil {
+		return fmt.Errorf("failed to find gaps: %w", err)
+	}
+	if len(gaps) == 0 {
+		return nil
+	}
+	logrus.Infof("[GAP] found [%d] gaps, first [%d-%d]", len(gaps), gaps[0].From, gaps[0].To)
+
+	filled := 0
+gapsLoop:
+	for _, g := range gaps {
+		for seqno := g.From; seqno <= g.To; seqno++ {
+			if filled >= blocksPerCheck || ctx.Err() != nil {
+				break gapsLoop
+			}
+			if err := s.Reparse(ctx, seqno); err != nil {
+				logrus.Errorf("[GAP] failed to fill block %d: %s", seqno, err)
+				continue
+			}
+			filled++
+		}
+	}
+	logrus.Infof("[GAP] filled [%d] blocks", filled)
+
+	return nil
+}
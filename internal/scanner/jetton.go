@@ -0,0 +1,220 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// errFakeJettonWallet is returned for contract claiming to be a wallet
+// of master which doesn't confirm it.
+var errFakeJettonWallet = errors.New("unverified jetton wallet")
+
+// jettonNotifyHandler decodes jetton transfer notifications with text comments.
+type jettonNotifyHandler struct {
+	s *Scanner
+}
+
+func (h jettonNotifyHandler) Name() string {
+	return "jetton_notify"
+}
+
+func (h jettonNotifyHandler) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+
+	var jn structures.JettonNotify
+	if err := tlb.LoadFromCell(&jn, msgIn.Body.BeginParse()); err != nil {
+		// opcode matched, so the body is malformed
+		logrus.Warnf("[JTN] failed to parse notification in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	var comment, payload string
+	if jn.FwdPayload != nil {
+		var err error
+		comment, payload, err = forwardPayload(ctx, tx, jn.FwdPayload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logrus.Infof("[JTN] %s from %s to %s, comment: %+v", jn.Amount, jn.Sender, msgIn.DstAddr, comment)
+
+	// notification is sent by recipient's jetton wallet
+	jettonMaster, err := h.s.jettonMaster(ctx, tx.Master, msgIn.SrcAddr)
+	if errors.Is(err, errFakeJettonWallet) {
+		logrus.Warnf("[JTN] skipping notification in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warnf("[JTN] failed to resolve jetton master of %s: %s", msgIn.SrcAddr, err)
+	}
+
+	walletCode, walletType, err := h.s.jettonWallet(ctx, tx.Master, msgIn.SrcAddr)
+	if err != nil {
+		logrus.Warnf("[JTN] failed to classify jetton wallet %s: %s", msgIn.SrcAddr, err)
+	}
+
+	return []handler.Event{{
+		Type:             storage.EventTypeJettonTransfer,
+		Opcode:           structures.OpJettonNotify,
+		JettonMaster:     jettonMaster,
+		JettonWalletCode: walletCode,
+		JettonWalletType: walletType,
+		Sender:           jn.Sender.String(),
+		Recipient:        msgIn.DstAddr.String(),
+		Amount:           jn.Amount.Nano().String(),
+		Comment:          comment,
+		Payload:          payload,
+	}}, nil
+}
+
+// jettonBurnHandler decodes burn requests sent by owner to jetton wallet,
+// they are stored as transfers without recipient.
+type jettonBurnHandler struct {
+	s *Scanner
+}
+
+func (h jettonBurnHandler) Name() string {
+	return "jetton_burn"
+}
+
+func (h jettonBurnHandler) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+
+	var jb structures.JettonBurn
+	if err := tlb.LoadFromCell(&jb, msgIn.Body.BeginParse()); err != nil {
+		logrus.Warnf("[JTN] failed to parse burn in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+
+	logrus.Infof("[JTN] %s burned by %s", jb.Amount, msgIn.SrcAddr)
+
+	// burn is sent to owner's wallet
+	jettonMaster, err := h.s.jettonMaster(ctx, tx.Master, msgIn.DstAddr)
+	if errors.Is(err, errFakeJettonWallet) {
+		logrus.Warnf("[JTN] skipping burn in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warnf("[JTN] failed to resolve jetton master of %s: %s", msgIn.DstAddr, err)
+	}
+
+	walletCode, walletType, err := h.s.jettonWallet(ctx, tx.Master, msgIn.DstAddr)
+	if err != nil {
+		logrus.Warnf("[JTN] failed to classify jetton wallet %s: %s", msgIn.DstAddr, err)
+	}
+
+	var customPayload string
+	if jb.CustomPayload != nil {
+		customPayload = base64.StdEncoding.EncodeToString(jb.CustomPayload.ToBOC())
+	}
+
+	return []handler.Event{{
+		Type:             storage.EventTypeJettonTransfer,
+		Opcode:           structures.OpJettonBurn,
+		JettonMaster:     jettonMaster,
+		JettonWalletCode: walletCode,
+		JettonWalletType: walletType,
+		Sender:           msgIn.SrcAddr.String(),
+		Amount:           jb.Amount.Nano().String(),
+		CustomPayload:    customPayload,
+	}}, nil
+}
+
+// forwardPayload returns text comment of forward payload. Payloads which
+// are not comments are returned as base64 BOC, empty ones are skipped.
+func forwardPayload(ctx context.Context, tx *handler.Tx, c *cell.Cell) (string, string, error) {
+	raw := base64.StdEncoding.EncodeToString(c.ToBOC())
+
+	// payload in ref can be exotic, e.g. library cell
+	resolved, err := handler.ResolveCell(ctx, tx.API, c)
+	if err != nil {
+		var cellErr *handler.UnresolvedCellError
+		if errors.As(err, &cellErr) {
+			logrus.Debugf("[JTN] passing unresolved forward payload in tx %x as is", tx.Tx.Hash)
+			return "", raw, nil
+		}
+		return "", "", err
+	}
+	if resolved.BitsSize() == 0 && resolved.RefsNum() == 0 {
+		return "", "", nil
+	}
+
+	sl := resolved.BeginParse()
+	op, err := sl.LoadUInt(32)
+	if err != nil || op != 0 {
+		return "", raw, nil
+	}
+	comment, err := sl.LoadStringSnake()
+	if err != nil {
+		logrus.Debugf("[JTN] failed to parse forward payload comment in tx %x: %s", tx.Tx.Hash, err)
+		return "", raw, nil
+	}
+
+	return comment, "", nil
+}
+
+// jettonMaster returns master contract of jetton wallet. Any contract
+// can claim to be a wallet of any master, so the claim is verified by
+// asking the master for the owner's wallet address. Only verified pairs
+// are cached, because wallet's master never changes.
+func (s *Scanner) jettonMaster(ctx context.Context, block *ton.BlockIDExt, wallet *address.Address) (string, error) {
+	key := wallet.String()
+	if master, ok := s.jettonMasters.Load(key); ok {
+		return master, nil
+	}
+
+	api := s.blockAPI(block.SeqNo)
+	res, err := api.RunGetMethod(ctx, block, wallet, "get_wallet_data")
+	if err != nil {
+		return "", fmt.Errorf("failed to run get_wallet_data: %w", err)
+	}
+	ownerSl, err := res.Slice(1)
+	if err != nil {
+		return "", fmt.Errorf("failed to get owner from wallet data: %w", err)
+	}
+	owner, err := ownerSl.LoadAddr()
+	if err != nil {
+		return "", fmt.Errorf("failed to load owner address: %w", err)
+	}
+	masterSl, err := res.Slice(2)
+	if err != nil {
+		return "", fmt.Errorf("failed to get jetton master from wallet data: %w", err)
+	}
+	master, err := masterSl.LoadAddr()
+	if err != nil {
+		return "", fmt.Errorf("failed to load jetton master address: %w", err)
+	}
+
+	ownerCell := cell.BeginCell().MustStoreAddr(owner).EndCell()
+	res, err = api.RunGetMethod(ctx, block, master, "get_wallet_address", ownerCell.BeginParse())
+	if err != nil {
+		return "", fmt.Errorf("failed to run get_wallet_address of %s: %w", master, err)
+	}
+	walletSl, err := res.Slice(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to get wallet address from %s: %w", master, err)
+	}
+	expected, err := walletSl.LoadAddr()
+	if err != nil {
+		return "", fmt.Errorf("failed to load wallet address from %s: %w", master, err)
+	}
+	if !expected.Equals(wallet) {
+		return "", fmt.Errorf("%w: %s is not a wallet of %s", errFakeJettonWallet, wallet, master)
+	}
+
+	s.jettonMasters.Store(key, master.String())
+
+	return master.String(), nil
+}
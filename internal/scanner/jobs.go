@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scheduler"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	statsPruneInterval      = time.Hour
+	blockCachePruneInterval = time.Hour
+	metadataRefreshInterval = 6 * time.Hour
+	configRefreshInterval   = 5 * time.Minute
+	correctionRelayInterval = 5 * time.Second
+)
+
+// addJobs registers periodic jobs, jobs of disabled features
+// are registered disabled, so they are visible in status.
+func (s *Scanner) addJobs(cfg *app.Cfg, cache *blockCache) {
+	s.scheduler.Add("gap_detection", cfg.GapCheckInterval, s.fillGaps)
+
+	var interval time.Duration
+	if cfg.StatsRetention > 0 {
+		interval = statsPruneInterval
+	}
+	s.scheduler.Add("stats_prune", interval, func(context.Context) error {
+		return storage.DeleteStatsBefore(app.DB, time.Now().Add(-cfg.StatsRetention))
+	})
+
+	interval = 0
+	if cache != nil {
+		interval = blockCachePruneInterval
+	}
+	s.scheduler.Add("block_cache_prune", interval, func(context.Context) error {
+		cache.prune(blockCacheMaxAge)
+		return nil
+	})
+
+	// contract code can be changed, e.g. by upgradable jetton wallets
+	s.scheduler.Add("metadata_refresh", metadataRefreshInterval, func(context.Context) error {
+		s.codeHashes.Clear()
+		return nil
+	})
+
+	interval = 0
+	if cfg.CompactionFile != "" {
+		interval = configRefreshInterval
+	}
+	s.scheduler.Add("config_refresh", interval, func(context.Context) error {
+		c, err := loadCompactor(cfg.CompactionFile)
+		if err != nil {
+			return err
+		}
+		s.writer.compactor.Store(c)
+		logrus.Debugf("[SCN] reloaded compaction config %s", cfg.CompactionFile)
+		return nil
+	})
+
+	s.scheduler.Add("correction_relay", correctionRelayInterval, s.relayCorrections)
+
+	s.scheduler.Disable(cfg.DisabledJobs)
+}
+
+// Jobs returns state of periodic jobs.
+func (s *Scanner) Jobs() []scheduler.JobStatus {
+	return s.scheduler.Status()
+}
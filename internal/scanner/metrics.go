@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// amount column is numeric(78,0)
+const maxAmountDigits = 78
+
+// HandlerStats are counters of a transaction handler since start.
+type HandlerStats struct {
+	Name     string        `json:"name"`
+	Critical bool          `json:"critical"`
+	Shadow   bool          `json:"shadow"`
+	Calls    uint64        `json:"calls"`
+	Errors   uint64        `json:"errors"`
+	Invalid  uint64        `json:"invalid_events"` // dropped events which can't be stored
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// handlerMetrics collects per-handler stats. Errors of critical handlers
+// fail the block, errors of other handlers are only counted and logged.
+// Outputs of shadow handlers are recorded separately and not published.
+type handlerMetrics struct {
+	critical map[string]struct{}
+	shadow   map[string]struct{}
+
+	mu    sync.Mutex
+	stats map[string]*HandlerStats
+}
+
+func newHandlerMetrics(critical, shadow []string) *handlerMetrics {
+	m := &handlerMetrics{
+		critical: make(map[string]struct{}, len(critical)),
+		shadow:   make(map[string]struct{}, len(shadow)),
+		stats:    make(map[string]*HandlerStats),
+	}
+	for _, name := range critical {
+		m.critical[name] = struct{}{}
+	}
+	for _, name := range shadow {
+		m.shadow[name] = struct{}{}
+	}
+
+	return m
+}
+
+func (m *handlerMetrics) isCritical(name string) bool {
+	_, ok := m.critical[name]
+	return ok
+}
+
+func (m *handlerMetrics) isShadow(name string) bool {
+	_, ok := m.shadow[name]
+	return ok
+}
+
+// call runs handler, recovering its panics, and records the result.
+// Invalid events are dropped, handlers can be untrusted and one bad
+// event must not fail the insert of whole batch.
+func (m *handlerMetrics) call(ctx context.Context, h handler.TxHandler, tx *handler.Tx) (events []handler.Event, err error) {
+	start := time.Now()
+	var invalid uint64
+	defer func() {
+		if r := recover(); r != nil {
+			events, err = nil, fmt.Errorf("panic: %v", r)
+		}
+		m.record(h.Name(), time.Since(start), err, invalid)
+	}()
+
+	events, err = h.Handle(ctx, tx)
+	valid := events[:0]
+	for _, e := range events {
+		if err := validateEvent(h.Name(), &e); err != nil {
+			logrus.Warnf("[SCN] handler %s emitted invalid event in tx %x: %s", h.Name(), tx.Tx.Hash, err)
+			invalid++
+			continue
+		}
+		valid = append(valid, e)
+	}
+
+	return valid, err
+}
+
+// validateEvent checks values which db would reject, empty amount is
+// set to zero. Decoders may emit only types of their own namespace.
+func validateEvent(name string, e *handler.Event) error {
+	if !storage.IsEventType(e.Type) {
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	isDecoder := strings.HasPrefix(name, storage.DecoderEventPrefix)
+	if isDecoder != strings.HasPrefix(e.Type, storage.DecoderEventPrefix) ||
+		isDecoder && !strings.HasPrefix(e.Type, name+":") {
+		return fmt.Errorf("event type %q is not in namespace of handler", e.Type)
+	}
+	if e.Amount == "" {
+		e.Amount = "0"
+	}
+	if len(e.Amount) > maxAmountDigits {
+		return fmt.Errorf("amount has more than %d digits", maxAmountDigits)
+	}
+	for _, c := range e.Amount {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("amount %q is not a decimal number", e.Amount)
+		}
+	}
+
+	return nil
+}
+
+func (m *handlerMetrics) record(name string, d time.Duration, err error, invalid uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stats[name]
+	if !ok {
+		st = &HandlerStats{Name: name, Critical: m.isCritical(name), Shadow: m.isShadow(name)}
+		m.stats[name] = st
+	}
+	st.Calls++
+	st.Duration += d
+	st.Invalid += invalid
+	if err != nil {
+		st.Errors++
+	}
+}
+
+// HandlerStats returns stats of handlers called at least once, ordered by name.
+func (s *Scanner) HandlerStats() []HandlerStats {
+	m := s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]HandlerStats, 0, len(m.stats))
+	for _, st := range m.stats {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+
+	return stats
+}
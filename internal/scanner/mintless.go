@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// mintlessClaimHandler decodes mintless jetton claims: transfers sent by
+// owner to own jetton wallet with merkle proof of airdrop in custom payload.
+type mintlessClaimHandler struct {
+	s *Scanner
+}
+
+func (h mintlessClaimHandler) Name() string {
+	return "mintless_claim"
+}
+
+func (h mintlessClaimHandler) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+
+	var jt structures.JettonTransfer
+	if err := tlb.LoadFromCell(&jt, msgIn.Body.BeginParse()); err != nil {
+		logrus.Warnf("[MNT] failed to parse transfer in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	if jt.CustomPayload == nil {
+		return nil, nil
+	}
+	payload := jt.CustomPayload.BeginParse()
+	if op, err := payload.LoadUInt(32); err != nil || op != structures.OpMerkleAirdropClaim {
+		return nil, nil
+	}
+	proof, err := payload.LoadRefCell()
+	if err != nil {
+		logrus.Warnf("[MNT] claim without proof in tx %x", tx.Tx.Hash)
+		return nil, nil
+	}
+
+	// claim amount is taken from proof, transferred amount can be smaller
+	amount := "0"
+	var customPayload string
+	item, err := airdropItem(ctx, tx, proof, msgIn.SrcAddr)
+	if err != nil {
+		var cellErr *handler.UnresolvedCellError
+		var libErr *handler.LibraryFetchError
+		if errors.As(err, &cellErr) || errors.As(err, &libErr) {
+			return nil, err
+		}
+		logrus.Warnf("[MNT] failed to read airdrop item in tx %x: %s", tx.Tx.Hash, err)
+		// pass claim through, so consumers can read the proof themselves
+		customPayload = base64.StdEncoding.EncodeToString(jt.CustomPayload.ToBOC())
+	} else {
+		amount = item.Amount.Nano().String()
+	}
+
+	// claim is sent to owner's wallet, which must be confirmed by master
+	jettonMaster, err := h.s.jettonMaster(ctx, tx.Master, msgIn.DstAddr)
+	if errors.Is(err, errFakeJettonWallet) {
+		logrus.Warnf("[MNT] skipping claim in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warnf("[MNT] failed to resolve jetton master of %s: %s", msgIn.DstAddr, err)
+	}
+
+	logrus.Infof("[MNT] %s claimed %s of %s", msgIn.SrcAddr, amount, jettonMaster)
+
+	return []handler.Event{{
+		Type:          storage.EventTypeMintlessClaim,
+		Opcode:        structures.OpJettonTransfer,
+		JettonMaster:  jettonMaster,
+		Sender:        msgIn.SrcAddr.String(),
+		Recipient:     msgIn.DstAddr.String(),
+		Amount:        amount,
+		CustomPayload: customPayload,
+	}}, nil
+}
+
+// airdropItem looks up owner's entry in airdrop dict proven by merkle proof.
+func airdropItem(ctx context.Context, tx *handler.Tx, proof *cell.Cell, owner *address.Address) (*structures.AirdropItem, error) {
+	root, err := handler.ResolveCell(ctx, tx.API, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cell.BeginCell()
+	if err := key.StoreAddr(owner); err != nil {
+		return nil, err
+	}
+	value, err := root.AsDict(267).LoadValue(key.EndCell())
+	if err != nil {
+		return nil, fmt.Errorf("owner is not in proof: %w", err)
+	}
+
+	var item structures.AirdropItem
+	if err := tlb.LoadFromCell(&item, value); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
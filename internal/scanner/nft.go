@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// nftTransferHandler decodes TEP-62 ownership transfers sent by owner
+// to NFT item, comment is taken from forward payload.
+type nftTransferHandler struct{}
+
+func (h nftTransferHandler) Name() string {
+	return "nft_transfer"
+}
+
+func (h nftTransferHandler) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+
+	var nt structures.NftTransfer
+	if err := tlb.LoadFromCell(&nt, msgIn.Body.BeginParse()); err != nil {
+		logrus.Warnf("[NFT] failed to parse transfer in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	var comment, payload string
+	if nt.FwdPayload != nil {
+		var err error
+		comment, payload, err = forwardPayload(ctx, tx, nt.FwdPayload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logrus.Debugf("[NFT] %s from %s to %s", msgIn.DstAddr, msgIn.SrcAddr, nt.NewOwner)
+
+	return []handler.Event{{
+		Type:      storage.EventTypeNftTransfer,
+		Opcode:    structures.OpNftTransfer,
+		Sender:    msgIn.SrcAddr.String(),
+		Recipient: nt.NewOwner.String(),
+		NftItem:   msgIn.DstAddr.String(),
+		Amount:    "0",
+		Comment:   comment,
+		Payload:   payload,
+	}}, nil
+}
@@ -2,14 +2,15 @@ package scanner
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
-	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
 	"github.com/sirupsen/logrus"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
@@ -27,7 +28,7 @@ func (s *Scanner) processBlocks(ctx context.Context) {
 	delay := delayBase
 
 	for {
-		master, err := s.api.LookupBlock(
+		master, err := s.blockAPI(s.lastBlock.SeqNo).LookupBlock(
 			ctx,
 			s.lastBlock.Workchain,
 			s.lastBlock.Shard,
@@ -37,6 +38,9 @@ func (s *Scanner) processBlocks(ctx context.Context) {
 			delay = delayBase
 		}
 		if err != nil {
+			if s.markArchival(ctx, s.lastBlock.SeqNo, err) {
+				continue
+			}
 			if !errors.Is(err, ton.ErrBlockNotFound) {
 				logrus.Errorf("[SCN] failed to lookup master block %d: %s", s.lastBlock.SeqNo, err)
 			}
@@ -62,6 +66,9 @@ func (s *Scanner) processBlocks(ctx context.Context) {
 				retries++
 				continue
 			}
+			if s.markArchival(ctx, master.SeqNo, err) {
+				continue
+			}
 
 			time.Sleep(delay)
 			delay *= 2
@@ -73,57 +80,146 @@ func (s *Scanner) processBlocks(ctx context.Context) {
 	}
 }
 
+var errTxProcessing = errors.New("failed to process transactions")
+
 func (s *Scanner) processMcBlock(ctx context.Context, master *ton.BlockIDExt) error {
 	start := time.Now()
 
-	currentShards, err := s.api.GetBlockShardsInfo(ctx, master)
+	parseCtx := ctx
+	if s.blockTimeout > 0 {
+		var cancel context.CancelFunc
+		parseCtx, cancel = context.WithTimeout(ctx, s.blockTimeout)
+		defer cancel()
+		// dumped even if processing ignores cancellation
+		timer := time.AfterFunc(s.blockTimeout, func() {
+			s.dumpDiagnostics(master.SeqNo)
+		})
+		defer timer.Stop()
+	}
+
+	txCount, events, err := s.parseMcBlock(parseCtx, master, nil)
 	if err != nil {
+		if ctx.Err() == nil && errors.Is(parseCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("block processing timed out after %s: %w", s.blockTimeout, err)
+		}
+		if errors.Is(err, errTxProcessing) {
+			// start with next block, otherwise process will get stuck
+			s.lastBlock.SeqNo++
+		}
 		return err
 	}
 
-	shards := make(map[string]*ton.BlockIDExt, len(currentShards))
+	if err := s.addBlock(ctx, master, txCount, events); err != nil {
+		return err
+	}
 
-	for _, shard := range currentShards {
-		// unique key
-		key := fmt.Sprintf("%d:%d:%d", shard.Workchain, shard.Shard, shard.SeqNo)
-		shards[key] = shard
+	lastSeqno, err := s.getLastBlockSeqno(ctx)
+	if err != nil {
+		logrus.Infof("[SCN] block [%d] processed in [%.2fs] with [%d] transactions, [%d] events",
+			master.SeqNo,
+			time.Since(start).Seconds(),
+			txCount,
+			len(events),
+		)
+	} else {
+		logrus.Infof("[SCN] block [%d|%d] processed in [%.2fs] with [%d] transactions, [%d] events",
+			master.SeqNo,
+			lastSeqno,
+			time.Since(start).Seconds(),
+			txCount,
+			len(events),
+		)
+	}
 
-		if err := s.fillWithNotSeenShards(ctx, shards, shard); err != nil {
-			return err
-		}
+	return nil
+}
+
+// parseMcBlock loads all transactions of master block shards
+// and returns their number together with decoded events.
+// Only handlers accepted by filter are run, nil filter runs all.
+func (s *Scanner) parseMcBlock(
+	ctx context.Context,
+	master *ton.BlockIDExt,
+	filter handlerFilter,
+) (_ int, _ []storage.Event, err error) {
+	progress := &blockProgress{start: time.Now()}
+	s.inFlight.Store(master.SeqNo, progress)
+	defer s.inFlight.Delete(master.SeqNo)
+
+	api := s.blockAPI(master.SeqNo)
+
+	currentShards, err := s.blockCache.shards(ctx, api, master)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	shards, err := s.collectShards(ctx, api, currentShards)
+	if err != nil {
+		return 0, nil, err
+	}
+	s.shardsMu.Lock()
+	for _, shard := range currentShards {
 		s.lastShardsSeqNo[s.getShardID(shard)] = shard.SeqNo
 	}
+	s.shardsMu.Unlock()
 
+	progress.shards.Store(int64(len(shards)))
 	txs := make([]*tlb.Transaction, 0, len(shards))
 	for _, shard := range shards {
-		shardTxs, err := s.getTxsFromShard(ctx, shard)
+		shardTxs, err := s.getTxsFromShard(ctx, api, shard)
 		if err != nil {
-			return err
+			return 0, nil, err
 		}
 		txs = append(txs, shardTxs...)
+		progress.shardsLoaded.Add(1)
 	}
+	progress.txs.Store(int64(len(txs)))
+	defer func() {
+		// failed transactions skip and dead-letter the block, only
+		// interrupted and timed out blocks are retried with the cache
+		if err == nil || errors.Is(err, errTxProcessing) && ctx.Err() == nil {
+			s.dropCached(master, shards)
+		}
+	}()
 
 	var (
-		tmb  tomb.Tomb
-		wg   sync.WaitGroup
-		txDB = app.DB.Begin()
+		tmb    tomb.Tomb
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		events []storage.Event
 	)
 	// process transactions
 	tmb.Go(func() error {
+	txLoop:
 		for _, tx := range txs {
-			// break loop if there was transaction processing error
+			// stop if there was transaction processing error
 			select {
 			case <-tmb.Dying():
-				break
+				break txLoop
 			default:
 			}
 
+			if err := s.parseLimiter.acquire(ctx); err != nil {
+				tmb.Kill(err)
+				break
+			}
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				if err := s.processTx(tx); err != nil {
+				start := time.Now()
+				txEvents, err := s.processTx(ctx, master, tx, filter)
+				s.parseLimiter.release(time.Since(start), limiterError(err))
+				if err != nil {
 					tmb.Kill(err)
+					return
+				}
+				progress.txsProcessed.Add(1)
+				if len(txEvents) == 0 {
+					return
 				}
+				mu.Lock()
+				events = append(events, txEvents...)
+				mu.Unlock()
 			}()
 		}
 		wg.Wait()
@@ -132,89 +228,63 @@ func (s *Scanner) processMcBlock(ctx context.Context, master *ton.BlockIDExt) er
 
 	if err := tmb.Wait(); err != nil {
 		logrus.Errorf("[SCN] failed to process transactions: %s", err)
-		txDB.Rollback()
-		// start with next block, otherwise process will get stuck
-		s.lastBlock.SeqNo++
-		return err
-	}
-
-	if err := s.addBlock(master, txDB); err != nil {
-		return err
-	}
-
-	if err := txDB.Commit().Error; err != nil {
-		logrus.Errorf("[SCN] failed to commit txDB: %s", err)
-		return err
+		// timed out blocks are retried
+		if ctx.Err() == nil {
+			recordFailed(master.SeqNo, err)
+		}
+		return 0, nil, fmt.Errorf("%w: %w", errTxProcessing, err)
 	}
 
-	lastSeqno, err := s.getLastBlockSeqno(ctx)
+	configEvents, err := s.configEvents(ctx, api, master)
 	if err != nil {
-		logrus.Infof("[SCN] block [%d] processed in [%.2fs] with [%d] transactions",
-			master.SeqNo,
-			time.Since(start).Seconds(),
-			len(txs),
-		)
-	} else {
-		logrus.Infof("[SCN] block [%d|%d] processed in [%.2fs] with [%d] transactions",
-			master.SeqNo,
-			lastSeqno,
-			time.Since(start).Seconds(),
-			len(txs),
-		)
+		return 0, nil, fmt.Errorf("failed to check config: %w", err)
 	}
+	events = append(events, configEvents...)
 
-	return nil
+	return len(txs), events, nil
 }
-func (s *Scanner) getTxsFromShard(ctx context.Context, shard *ton.BlockIDExt) ([]*tlb.Transaction, error) {
+
+func (s *Scanner) getTxsFromShard(ctx context.Context, api *ton.APIClient, shard *ton.BlockIDExt) ([]*tlb.Transaction, error) {
 	var (
-		after    *ton.TransactionID3
-		more     = true
-		err      error
-		eg       errgroup.Group
-		txsShort []ton.TransactionShortInfo
-		mu       sync.Mutex
-		txs      []*tlb.Transaction
+		eg  errgroup.Group
+		mu  sync.Mutex
+		txs []*tlb.Transaction
 	)
 
-	for more {
-		txsShort, more, err = s.api.GetBlockTransactionsV2(
-			ctx,
-			shard,
-			100,
-			after,
-		)
-		if err != nil {
-			return nil, err
-		}
+	txsShort, err := s.blockCache.txs(ctx, api, shard)
+	if err != nil {
+		return nil, err
+	}
 
-		if more {
-			after = txsShort[len(txsShort)-1].ID3()
+	for _, txShort := range txsShort {
+		if err := s.fetchLimiter.acquire(ctx); err != nil {
+			_ = eg.Wait()
+			return nil, err
 		}
-
-		for _, txShort := range txsShort {
-			eg.Go(func() error {
-				tx, err := s.api.GetTransaction(
-					ctx,
-					shard,
-					address.NewAddress(0, 0, txShort.Account),
-					txShort.LT,
-				)
-				if err != nil {
-					if strings.Contains(err.Error(), "is not in db") {
-						return nil
-					}
-
-					logrus.Errorf("[SCN] failed to load tx: %s", err)
-					return err
+		eg.Go(func() error {
+			start := time.Now()
+			tx, err := api.GetTransaction(
+				ctx,
+				shard,
+				address.NewAddress(0, 0, txShort.Account),
+				txShort.LT,
+			)
+			s.fetchLimiter.release(time.Since(start), limiterError(err))
+			if err != nil {
+				if strings.Contains(err.Error(), "is not in db") {
+					return nil
 				}
 
-				mu.Lock()
-				defer mu.Unlock()
-				txs = append(txs, tx)
+				logrus.Errorf("[SCN] failed to load tx: %s", err)
+				return err
+			}
 
-				return nil
-			})
-		}
+			mu.Lock()
+			defer mu.Unlock()
+			txs = append(txs, tx)
+
+			return nil
+		})
 	}
 
 	if err := eg.Wait(); err != nil {
@@ -224,40 +294,112 @@ func (s *Scanner) getTxsFromShard(ctx context.Context, shard *ton.BlockIDExt) ([
 	return txs, nil
 }
 
-func (s *Scanner) processTx(tx *tlb.Transaction) error {
-	if tx.IO.In.MsgType != tlb.MsgTypeInternal {
-		return nil
+func (s *Scanner) processTx(
+	ctx context.Context,
+	master *ton.BlockIDExt,
+	tx *tlb.Transaction,
+	filter handlerFilter,
+) ([]storage.Event, error) {
+	if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return nil, nil
 	}
 
 	msgIn := tx.IO.In.AsInternal()
 	if msgIn.Body == nil {
-		return nil
+		return nil, nil
 	}
 
-	var jn structures.JettonNotify
-	if err := tlb.LoadFromCell(&jn, msgIn.Body.BeginParse()); err != nil {
-		// invalid transaction, magic is not correct (opcode)
-		return nil
+	api := s.blockAPI(master.SeqNo)
+	// decoders expect ordinary cells
+	body, err := handler.ResolveCell(ctx, api, msgIn.Body)
+	if err != nil {
+		var cellErr *handler.UnresolvedCellError
+		if errors.As(err, &cellErr) {
+			recordUnresolved(master.SeqNo, tx, "", cellErr)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to resolve message body: %w", err)
 	}
-	if jn.FwdPayload == nil {
-		return nil
+	if body != msgIn.Body {
+		msg := *msgIn
+		msg.Body = body
+		msgIn = &msg
 	}
 
-	fwdPayload := jn.FwdPayload.BeginParse()
-	op, err := fwdPayload.LoadUInt(32)
-	if err != nil {
-		return nil
+	htx := &handler.Tx{
+		Master: master,
+		API:    api,
+		Tx:     tx,
+		Msg:    msgIn,
 	}
-	if op != 0 {
-		logrus.Debugf("[SCN] invalid opcode: %x", op)
-		return nil
+	if op, err := msgIn.Body.BeginParse().LoadUInt(32); err == nil {
+		htx.Opcode = uint32(op)
 	}
-	comment, err := fwdPayload.LoadStringSnake()
-	if err != nil {
-		return fmt.Errorf("[JTN] failed to parse forward payload comment: %s", err)
+
+	var codeHash []byte
+	if s.handlers.HasCodeHandlers() {
+		var err error
+		codeHash, err = s.codeHash(ctx, master, msgIn.DstAddr)
+		if err != nil {
+			logrus.Warnf("[SCN] failed to get code hash of %s: %s", msgIn.DstAddr, err)
+		}
+	}
+
+	var events []storage.Event
+	for _, h := range s.handlers.Handlers(htx.Opcode, codeHash) {
+		if filter != nil && !filter(h.Name()) {
+			continue
+		}
+		decoded, err := s.metrics.call(ctx, h, htx)
+		if s.metrics.isShadow(h.Name()) {
+			recordShadow(master.SeqNo, tx, h.Name(), decoded, err)
+			continue
+		}
+		if err != nil {
+			var cellErr *handler.UnresolvedCellError
+			if errors.As(err, &cellErr) {
+				recordUnresolved(master.SeqNo, tx, h.Name(), cellErr)
+				continue
+			}
+			// transient, the block is processed again instead of losing events
+			var libErr *handler.LibraryFetchError
+			if errors.As(err, &libErr) {
+				return nil, &handlerError{handler: h.Name(), err: err}
+			}
+			if s.metrics.isCritical(h.Name()) {
+				return nil, &handlerError{handler: h.Name(), err: err}
+			}
+			logrus.Errorf("[SCN] handler %s failed on tx %x: %s", h.Name(), tx.Hash, err)
+			continue
+		}
+		for _, e := range decoded {
+			events = append(events, storage.Event{
+				Type:             e.Type,
+				SeqNo:            master.SeqNo,
+				LT:               tx.LT,
+				UTime:            tx.Now,
+				TxHash:           hex.EncodeToString(tx.Hash),
+				Opcode:           e.Opcode,
+				JettonMaster:     e.JettonMaster,
+				JettonWalletCode: e.JettonWalletCode,
+				JettonWalletType: e.JettonWalletType,
+				Sender:           e.Sender,
+				Recipient:        e.Recipient,
+				NftItem:          e.NftItem,
+				Amount:           e.Amount,
+				Comment:          storage.EncryptedString(e.Comment),
+				Payload:          e.Payload,
+				CustomPayload:    e.CustomPayload,
+				Success:          isTxSuccess(tx),
+			})
+		}
 	}
 
-	logrus.Infof("[JTN] %s from %s to %s, comment: %+v", jn.Amount, jn.Sender, msgIn.DstAddr, comment)
+	if len(events) > 0 {
+		if e := s.crossCheck(ctx, htx); e != nil {
+			events = append(events, *e)
+		}
+	}
 
-	return nil
+	return events, nil
 }
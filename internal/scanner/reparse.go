@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// Reparse processes already stored master block again. Its stored events
+// are soft deleted and replaced with a new revision, subscribers get
+// a correction for the block. Without broker the correction is stored
+// and published by running scanner. It returns once the block is stored.
+func (s *Scanner) Reparse(ctx context.Context, seqno uint32) error {
+	if err := s.reparse(ctx, seqno, nil); err != nil {
+		return err
+	}
+	if err := resolveFailed(ctx, seqno, ""); err != nil {
+		logrus.Errorf("[SCN] failed to resolve failed block %d: %s", seqno, err)
+	}
+
+	return nil
+}
+
+func (s *Scanner) reparse(ctx context.Context, seqno uint32, filter handlerFilter) error {
+	start := time.Now()
+
+	master, err := s.lookupMaster(ctx, seqno)
+	if err != nil {
+		return err
+	}
+
+	txCount, events, err := s.parseMcBlock(ctx, master, filter)
+	if err != nil {
+		return err
+	}
+
+	stored := make(chan error, 1)
+	b := blockBatch{
+		block:   newBlock(master, txCount),
+		events:  events,
+		reparse: true,
+		stored:  stored,
+	}
+	if err := s.writer.push(ctx, b); err != nil {
+		return err
+	}
+	select {
+	case err := <-stored:
+		if err != nil {
+			return fmt.Errorf("failed to store block: %w", err)
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	logrus.Infof("[SCN] block [%d] reparsed in [%.2fs] with [%d] transactions, [%d] events",
+		master.SeqNo,
+		time.Since(start).Seconds(),
+		txCount,
+		len(events),
+	)
+
+	return nil
+}
+
+// lookupMaster finds master block by seqno, falling back to archive nodes.
+func (s *Scanner) lookupMaster(ctx context.Context, seqno uint32) (*ton.BlockIDExt, error) {
+	master, err := s.blockAPI(seqno).LookupBlock(ctx, address.MasterchainID, math.MinInt64, seqno)
+	if err != nil && s.markArchival(ctx, seqno, err) {
+		master, err = s.blockAPI(seqno).LookupBlock(ctx, address.MasterchainID, math.MinInt64, seqno)
+	}
+
+	return master, err
+}
@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"slices"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// Report describes scanner state on shutdown.
+type Report struct {
+	// last master block stored in db, scanning resumes from the next one
+	LastCommitted uint32
+	// blocks pushed to writer but not stored
+	Uncommitted int
+	// blocks which were being parsed and are discarded
+	Discarded []uint32
+}
+
+// Report returns current scanner state, call it after Stop.
+func (s *Scanner) Report() Report {
+	r := Report{
+		LastCommitted: s.writer.lastCommitted.Load(),
+		Uncommitted:   int(s.writer.pending.Load()),
+	}
+	if r.LastCommitted == 0 {
+		// nothing was stored since start
+		var last storage.Block
+		if err := app.DB.Last(&last).Error; err == nil {
+			r.LastCommitted = last.SeqNo
+		}
+	}
+	s.inFlight.Range(func(key, _ any) bool {
+		r.Discarded = append(r.Discarded, key.(uint32))
+		return true
+	})
+	slices.Sort(r.Discarded)
+
+	return r
+}
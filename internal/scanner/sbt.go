@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// sbtHandler decodes TEP-85 soulbound token ops sent to SBT items.
+type sbtHandler struct{}
+
+func (h sbtHandler) Name() string {
+	return "sbt"
+}
+
+func (h sbtHandler) Handle(_ context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+	e := handler.Event{
+		Opcode:  tx.Opcode,
+		Sender:  msgIn.SrcAddr.String(),
+		NftItem: msgIn.DstAddr.String(),
+		Amount:  "0",
+	}
+
+	var err error
+	switch tx.Opcode {
+	case structures.OpSBTProveOwnership:
+		var po structures.SBTProveOwnership
+		if err = tlb.LoadFromCell(&po, msgIn.Body.BeginParse()); err == nil {
+			e.Type = storage.EventTypeSBTProveOwnership
+			e.Recipient = po.Dest.String()
+		}
+	case structures.OpSBTRevoke:
+		var r structures.SBTRevoke
+		if err = tlb.LoadFromCell(&r, msgIn.Body.BeginParse()); err == nil {
+			e.Type = storage.EventTypeSBTRevoke
+		}
+	case structures.OpSBTDestroy:
+		var d structures.SBTDestroy
+		if err = tlb.LoadFromCell(&d, msgIn.Body.BeginParse()); err == nil {
+			e.Type = storage.EventTypeSBTDestroy
+		}
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warnf("[SBT] failed to parse op %x in tx %x: %s", tx.Opcode, tx.Tx.Hash, err)
+		return nil, nil
+	}
+
+	logrus.Debugf("[SBT] %s of %s by %s", e.Type, e.NftItem, e.Sender)
+
+	return []handler.Event{e}, nil
+}
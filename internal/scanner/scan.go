@@ -2,10 +2,16 @@ package scanner
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/scheduler"
 	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/wasm"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
 	"github.com/sirupsen/logrus"
 	"github.com/xssnick/tonutils-go/liteclient"
 	"github.com/xssnick/tonutils-go/ton"
@@ -15,26 +21,164 @@ type Scanner struct {
 	api             *ton.APIClient
 	lastBlock       storage.Block
 	lastShardsSeqNo map[string]uint32
-	Client          *liteclient.ConnectionPool
+	shardsMu        sync.Mutex
+	writer          *writer
+	archive         *archive
+	discovery       *discovery
+	// jetton masters by verified wallet address
+	jettonMasters *lru[string, string]
+	// code hashes by address, refreshed by metadata_refresh job
+	codeHashes *lru[string, []byte]
+	// jetton wallet types by code hash
+	walletTypes *lru[string, string]
+	handlers    *handler.Registry
+	metrics     *handlerMetrics
+	wasm        *wasm.Runtime
+	scheduler   *scheduler.Scheduler
+	stats       *stats
+	config      *configMonitor
+	// master blocks being parsed
+	inFlight       sync.Map
+	blockTimeout   time.Duration
+	diagnosticsDir string
+	blockCache     *blockCache
+	fetchLimiter   *aimdLimiter
+	parseLimiter   *aimdLimiter
+	watches        chan struct{}
+	// history of subscribed accounts older than this isn't backfilled
+	backfillDepth time.Duration
+	Client        *liteclient.ConnectionPool
 }
 
-func NewScanner(ctx context.Context, cfg *liteclient.GlobalConfig) (*Scanner, error) {
+// NewScanner creates scanner publishing committed events to broker.
+// Standalone tools pass nil broker, their corrections are stored for
+// running scanner to publish.
+func NewScanner(ctx context.Context, cfg *app.Cfg, broker *stream.Broker) (*Scanner, error) {
+	var comp *compactor
+	if cfg.CompactionFile != "" {
+		var err error
+		comp, err = loadCompactor(cfg.CompactionFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cache *blockCache
+	if cfg.BlockCacheDir != "" {
+		var err error
+		cache, err = newBlockCache(cfg.BlockCacheDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	netCfg, err := liteclient.GetConfigFromUrl(ctx, app.TestnetCfgURL)
+	if err != nil {
+		return nil, err
+	}
+
 	client := liteclient.NewConnectionPool()
-	if err := client.AddConnectionsFromConfigUrl(ctx, app.TestnetCfgURL); err != nil {
+	var disc *discovery
+	if cfg.Discovery.Enabled {
+		disc, err = newDiscovery(client, netCfg, cfg.Discovery.Keys, cfg.Discovery.MinAlive)
+		if err != nil {
+			return nil, err
+		}
+		if err := disc.connect(ctx, netCfg); err != nil {
+			disc.stop()
+			return nil, err
+		}
+		go disc.run(ctx)
+	} else if err := client.AddConnectionsFromConfig(ctx, netCfg); err != nil {
 		return nil, err
 	}
-	api := ton.NewAPIClient(client)
+	st := newStats()
+	api := ton.NewAPIClient(countingClient{LiteClient: client, stats: st})
 
-	return &Scanner{
+	var arch *archive
+	if cfg.ArchiveCfgURL != "" {
+		arch, err = newArchive(ctx, cfg.ArchiveCfgURL, st)
+		if err != nil {
+			client.Stop()
+			if disc != nil {
+				disc.stop()
+			}
+			return nil, err
+		}
+	}
+
+	go st.run()
+	w := newWriter(broker, st, comp)
+	if cfg.DedupWindow > 0 {
+		w.dedup = newNotifyDedup(cfg.DedupWindow)
+	}
+	go w.run()
+
+	s := &Scanner{
 		api:             api,
 		lastBlock:       storage.Block{},
 		lastShardsSeqNo: make(map[string]uint32),
+		writer:          w,
+		archive:         arch,
+		discovery:       disc,
+		jettonMasters:   newLRU[string, string](jettonMasterCacheSize),
+		codeHashes:      newLRU[string, []byte](codeHashCacheSize),
+		walletTypes:     newLRU[string, string](walletTypeCacheSize),
+		handlers:        handler.NewRegistry(),
+		metrics:         newHandlerMetrics(cfg.CriticalHandlers, cfg.ShadowHandlers),
+		scheduler:       scheduler.New(),
+		blockTimeout:    cfg.BlockTimeout,
+		backfillDepth:   cfg.AccountBackfillDepth,
+		diagnosticsDir:  cfg.DiagnosticsDir,
+		blockCache:      cache,
+		fetchLimiter:    newAIMDLimiter("fetch", cfg.MaxConcurrency),
+		parseLimiter:    newAIMDLimiter("parse", cfg.MaxConcurrency),
+		stats:           st,
+		watches:         make(chan struct{}, 1),
 		Client:          client,
-	}, nil
+	}
+	if len(cfg.ConfigParams) > 0 {
+		s.config = newConfigMonitor(cfg.ConfigParams)
+	}
+	s.registerHandlers()
+
+	s.addJobs(cfg, cache)
+
+	if cfg.PluginsDir != "" {
+		loaded, err := handler.LoadPlugins(cfg.PluginsDir, s.handlers)
+		if err != nil {
+			s.Stop(context.Background())
+			return nil, err
+		}
+		logrus.Infof("[SCN] loaded plugins: %v", loaded)
+	}
+	if cfg.WasmDir != "" {
+		if err := s.loadWasmDecoders(ctx, cfg.WasmDir); err != nil {
+			s.Stop(context.Background())
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
-func (s *Scanner) Stop() {
+// Stop stops clients and flushes the writer until ctx is done, blocks
+// left unstored are reported as uncommitted by Report.
+func (s *Scanner) Stop(ctx context.Context) {
 	s.Client.Stop()
+	if s.archive != nil {
+		s.archive.client.Stop()
+	}
+	if s.discovery != nil {
+		s.discovery.stop()
+	}
+	if s.wasm != nil {
+		if err := s.wasm.Close(context.Background()); err != nil {
+			logrus.Errorf("[SCN] failed to close wasm runtime: %s", err)
+		}
+	}
+	s.writer.stop(ctx)
+	s.stats.stop()
 }
 
 func (s *Scanner) updateLastBlock(ctx context.Context) {
@@ -53,6 +197,9 @@ func (s *Scanner) updateLastBlock(ctx context.Context) {
 func (s *Scanner) Listen(ctx context.Context) {
 	logrus.Info("[SCN] start scanning blocks")
 
+	s.scheduler.Run(ctx)
+	go s.runAccountBackfills(ctx)
+
 	err := app.DB.Last(&s.lastBlock).Error
 	if err == nil {
 		// process next block
@@ -63,7 +210,7 @@ func (s *Scanner) Listen(ctx context.Context) {
 		s.updateLastBlock(ctx)
 	}
 
-	master, err := s.api.LookupBlock(
+	master, err := s.blockAPI(s.lastBlock.SeqNo).LookupBlock(
 		ctx,
 		s.lastBlock.Workchain,
 		s.lastBlock.Shard,
@@ -71,7 +218,16 @@ func (s *Scanner) Listen(ctx context.Context) {
 	)
 	retries := 0
 	for err != nil {
-		logrus.Error("[SCN] failed to lookup master block %d: ", s.lastBlock.SeqNo, err)
+		if s.markArchival(ctx, s.lastBlock.SeqNo, err) {
+			master, err = s.blockAPI(s.lastBlock.SeqNo).LookupBlock(
+				ctx,
+				s.lastBlock.Workchain,
+				s.lastBlock.Shard,
+				s.lastBlock.SeqNo,
+			)
+			continue
+		}
+		logrus.Errorf("[SCN] failed to lookup master block %d: %s", s.lastBlock.SeqNo, err)
 		retries++
 		time.Sleep(2 * time.Second)
 		// find last block from mc after some tries
@@ -79,7 +235,7 @@ func (s *Scanner) Listen(ctx context.Context) {
 		if retries >= 5 {
 			s.updateLastBlock(ctx)
 		}
-		master, err = s.api.LookupBlock(
+		master, err = s.blockAPI(s.lastBlock.SeqNo).LookupBlock(
 			ctx,
 			s.lastBlock.Workchain,
 			s.lastBlock.Shard,
@@ -87,7 +243,7 @@ func (s *Scanner) Listen(ctx context.Context) {
 		)
 	}
 
-	firstShards, err := s.api.GetBlockShardsInfo(ctx, master)
+	firstShards, err := s.blockAPI(master.SeqNo).GetBlockShardsInfo(ctx, master)
 	for err != nil {
 		logrus.Error("[SCN] failed to get first shards: ", err)
 		time.Sleep(time.Second)
@@ -99,3 +255,50 @@ func (s *Scanner) Listen(ctx context.Context) {
 
 	s.processBlocks(ctx)
 }
+
+// registerHandlers registers built-in handlers.
+func (s *Scanner) registerHandlers() {
+	s.handlers.RegisterOpcode(structures.OpJettonNotify, jettonNotifyHandler{s: s})
+	s.handlers.RegisterOpcode(structures.OpJettonTransfer, mintlessClaimHandler{s: s})
+	s.handlers.RegisterOpcode(structures.OpJettonBurn, jettonBurnHandler{s: s})
+	for _, op := range []uint32{
+		structures.OpTextComment,
+		structures.OpEncryptedComment,
+		structures.OpBounced,
+	} {
+		s.handlers.RegisterOpcode(op, tonTransferHandler{})
+	}
+	s.handlers.RegisterOpcode(structures.OpNftTransfer, nftTransferHandler{})
+	for _, op := range []uint32{
+		structures.OpJettonNotify,
+		structures.OpDedustNativeSwap,
+	} {
+		s.handlers.RegisterOpcode(op, swapHandler{s: s})
+	}
+	for _, op := range []uint32{
+		structures.OpSBTProveOwnership,
+		structures.OpSBTRevoke,
+		structures.OpSBTDestroy,
+	} {
+		s.handlers.RegisterOpcode(op, sbtHandler{})
+	}
+}
+
+func (s *Scanner) loadWasmDecoders(ctx context.Context, dir string) error {
+	rt, err := wasm.NewRuntime(ctx)
+	if err != nil {
+		return err
+	}
+	s.wasm = rt
+
+	decoders, err := rt.LoadDir(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range decoders {
+		s.handlers.RegisterOpcode(d.Opcode(), d)
+		logrus.Infof("[SCN] loaded %s for opcode %x", d.Name(), d.Opcode())
+	}
+
+	return nil
+}
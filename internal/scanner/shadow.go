@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// recordShadow stores output of handler running in shadow mode,
+// failures are only logged because shadow handlers must not affect scanning.
+func recordShadow(seqno uint32, tx *tlb.Transaction, name string, events []handler.Event, handleErr error) {
+	txHash := hex.EncodeToString(tx.Hash)
+
+	var shadow []storage.ShadowEvent
+	if handleErr != nil {
+		shadow = append(shadow, storage.ShadowEvent{
+			Handler: name,
+			SeqNo:   seqno,
+			LT:      tx.LT,
+			TxHash:  txHash,
+			Error:   handleErr.Error(),
+		})
+	}
+	for _, e := range events {
+		shadow = append(shadow, storage.ShadowEvent{
+			Handler:       name,
+			SeqNo:         seqno,
+			LT:            tx.LT,
+			TxHash:        txHash,
+			Type:          e.Type,
+			Opcode:        e.Opcode,
+			JettonMaster:  e.JettonMaster,
+			Sender:        e.Sender,
+			Recipient:     e.Recipient,
+			NftItem:       e.NftItem,
+			Amount:        e.Amount,
+			Comment:       storage.EncryptedString(e.Comment),
+			Payload:       e.Payload,
+			CustomPayload: e.CustomPayload,
+		})
+	}
+	if len(shadow) == 0 {
+		return
+	}
+
+	if err := app.DB.Create(&shadow).Error; err != nil {
+		logrus.Errorf("[SCN] failed to record shadow output of %s: %s", name, err)
+	}
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/tl"
+	"github.com/xssnick/tonutils-go/ton"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const statsFlushInterval = time.Minute
+
+// stats counts scanner throughput and stores it per minute.
+type stats struct {
+	blocks        atomic.Uint64
+	txs           atomic.Uint64
+	events        atomic.Uint64
+	liteserverReq atomic.Uint64
+	suppressed    atomic.Uint64
+
+	// liteserver queries waiting for response, for diagnostics
+	callID atomic.Uint64
+	calls  sync.Map
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+func newStats() *stats {
+	return &stats{
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (st *stats) addBlocks(blocks []storage.Block, events int) {
+	var txs uint64
+	for _, b := range blocks {
+		if b.TxCount != nil {
+			txs += uint64(*b.TxCount)
+		}
+	}
+	st.blocks.Add(uint64(len(blocks)))
+	st.txs.Add(txs)
+	st.events.Add(uint64(events))
+}
+
+func (st *stats) run() {
+	defer close(st.done)
+
+	ticker := time.NewTicker(statsFlushInterval)
+	defer ticker.Stop()
+
+	minute := time.Now().Truncate(time.Minute)
+	for {
+		select {
+		case <-ticker.C:
+			st.flush(minute)
+			minute = time.Now().Truncate(time.Minute)
+		case <-st.quit:
+			st.flush(minute)
+			return
+		}
+	}
+}
+
+func (st *stats) stop() {
+	close(st.quit)
+	<-st.done
+}
+
+func (st *stats) flush(minute time.Time) {
+	s := storage.Stat{
+		Minute:        minute,
+		Blocks:        st.blocks.Swap(0),
+		Txs:           st.txs.Swap(0),
+		Events:        st.events.Swap(0),
+		LiteserverReq: st.liteserverReq.Swap(0),
+		Suppressed:    st.suppressed.Swap(0),
+	}
+	if err := storage.AddStat(app.DB, s); err != nil {
+		// counters are lost, stats are best effort
+		logrus.Errorf("[STS] failed to store stats: %s", err)
+	}
+}
+
+// liteCall is a liteserver query waiting for response.
+type liteCall struct {
+	query string
+	start time.Time
+}
+
+// countingClient counts queries sent to liteservers
+// and tracks the ones in flight.
+type countingClient struct {
+	ton.LiteClient
+	stats *stats
+}
+
+func (c countingClient) QueryLiteserver(ctx context.Context, payload tl.Serializable, result tl.Serializable) error {
+	c.stats.liteserverReq.Add(1)
+
+	id := c.stats.callID.Add(1)
+	c.stats.calls.Store(id, liteCall{query: fmt.Sprintf("%T", payload), start: time.Now()})
+	defer c.stats.calls.Delete(id)
+
+	return c.LiteClient.QueryLiteserver(ctx, payload, result)
+}
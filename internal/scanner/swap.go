@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// swapHandler decodes swap requests received by DEX: jetton notifications
+// to STON.fi router or DeDust vault with swap forward payload, and swaps
+// of TON sent to DeDust native vault. Event opcode is the swap op, so DEX
+// can be told, amount is the offered one, jetton master is empty for TON.
+type swapHandler struct {
+	s *Scanner
+}
+
+func (h swapHandler) Name() string {
+	return "swap"
+}
+
+func (h swapHandler) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	if tx.Opcode == structures.OpDedustNativeSwap {
+		return h.nativeSwap(tx)
+	}
+
+	msgIn := tx.Msg
+	var jn structures.JettonNotify
+	if err := tlb.LoadFromCell(&jn, msgIn.Body.BeginParse()); err != nil || jn.FwdPayload == nil {
+		// malformed notifications are reported by jetton_notify
+		return nil, nil
+	}
+	payload, err := handler.ResolveCell(ctx, tx.API, jn.FwdPayload)
+	if err != nil {
+		var cellErr *handler.UnresolvedCellError
+		if errors.As(err, &cellErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	op, err := payload.BeginParse().LoadUInt(32)
+	if err != nil {
+		return nil, nil
+	}
+
+	e := handler.Event{
+		Type:   storage.EventTypeSwap,
+		Opcode: uint32(op),
+		Sender: jn.Sender.String(),
+		Amount: jn.Amount.Nano().String(),
+	}
+	switch op {
+	case structures.OpStonfiSwap:
+		var swap structures.StonfiSwap
+		if err := tlb.LoadFromCell(&swap, payload.BeginParse()); err != nil {
+			logrus.Warnf("[SWP] failed to parse STON.fi swap in tx %x: %s", tx.Tx.Hash, err)
+			return nil, nil
+		}
+		e.Recipient = swap.ToAddress.String()
+	case structures.OpDedustJettonSwap:
+		var swap structures.DedustJettonSwap
+		if err := tlb.LoadFromCell(&swap, payload.BeginParse()); err != nil {
+			logrus.Warnf("[SWP] failed to parse DeDust swap in tx %x: %s", tx.Tx.Hash, err)
+			return nil, nil
+		}
+		e.Recipient = swapRecipient(swap.Params.Recipient, jn.Sender)
+	default:
+		return nil, nil
+	}
+
+	// offered jettons are sent by DEX's jetton wallet
+	jettonMaster, err := h.s.jettonMaster(ctx, tx.Master, msgIn.SrcAddr)
+	if errors.Is(err, errFakeJettonWallet) {
+		logrus.Warnf("[SWP] skipping swap in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warnf("[SWP] failed to resolve jetton master of %s: %s", msgIn.SrcAddr, err)
+	}
+	e.JettonMaster = jettonMaster
+
+	logrus.Debugf("[SWP] %s offered %s of %s to %s", e.Sender, e.Amount, e.JettonMaster, msgIn.DstAddr)
+
+	return []handler.Event{e}, nil
+}
+
+func (h swapHandler) nativeSwap(tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+
+	var swap structures.DedustNativeSwap
+	if err := tlb.LoadFromCell(&swap, msgIn.Body.BeginParse()); err != nil {
+		logrus.Warnf("[SWP] failed to parse DeDust native swap in tx %x: %s", tx.Tx.Hash, err)
+		return nil, nil
+	}
+
+	logrus.Debugf("[SWP] %s offered %s TON to %s", msgIn.SrcAddr, swap.Amount, msgIn.DstAddr)
+
+	return []handler.Event{{
+		Type:      storage.EventTypeSwap,
+		Opcode:    structures.OpDedustNativeSwap,
+		Sender:    msgIn.SrcAddr.String(),
+		Recipient: swapRecipient(swap.Params.Recipient, msgIn.SrcAddr),
+		Amount:    swap.Amount.Nano().String(),
+	}}, nil
+}
+
+// swapRecipient returns recipient of swap output, sender if it's not set.
+func swapRecipient(recipient, sender *address.Address) string {
+	if recipient == nil || recipient.IsAddrNone() {
+		return sender.String()
+	}
+
+	return recipient.String()
+}
@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/testvectors"
+)
+
+const testJettonMaster = "EQAKNnuSzwsDff2Jlg7oMtVvf8FRaBu0HlNpDndvV4aZimBb"
+
+// testWalletCode is a code hash of jetton wallets of vectors.
+var testWalletCode = make([]byte, 32)
+
+// vectorScanner returns scanner with built-in handlers and chain state of
+// vector contracts cached, so handlers never query liteserver.
+func vectorScanner(t *testing.T, vectors []testvectors.Vector) *Scanner {
+	t.Helper()
+
+	s := &Scanner{
+		jettonMasters: newLRU[string, string](jettonMasterCacheSize),
+		codeHashes:    newLRU[string, []byte](codeHashCacheSize),
+		walletTypes:   newLRU[string, string](walletTypeCacheSize),
+		handlers:      handler.NewRegistry(),
+	}
+	s.registerHandlers()
+	for _, v := range vectors {
+		tx, err := v.Tx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, addr := range []string{tx.Msg.SrcAddr.String(), tx.Msg.DstAddr.String()} {
+			s.jettonMasters.Store(addr, testJettonMaster)
+			s.codeHashes.Store(addr, testWalletCode)
+		}
+	}
+	s.walletTypes.Store(hex.EncodeToString(testWalletCode), storage.JettonWalletStandard)
+
+	return s
+}
+
+func TestVectors(t *testing.T) {
+	vectors, err := testvectors.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) == 0 {
+		t.Fatal("no vectors")
+	}
+	s := vectorScanner(t, vectors)
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			tx, err := v.Tx()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var events []handler.Event
+			for _, h := range s.handlers.Handlers(tx.Opcode, nil) {
+				hEvents, err := h.Handle(context.Background(), tx)
+				if err != nil {
+					t.Fatalf("handler %s: %s", h.Name(), err)
+				}
+				for i := range hEvents {
+					e := &hEvents[i]
+					if e.JettonMaster != "" && e.JettonMaster != testJettonMaster {
+						t.Errorf("unexpected jetton master %s", e.JettonMaster)
+					}
+					if err := validateEvent(h.Name(), e); err != nil {
+						t.Errorf("invalid event: %s", err)
+					}
+				}
+				events = append(events, hEvents...)
+			}
+			if err := v.Compare(events); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
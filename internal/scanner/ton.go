@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/structures"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+// tonTransferHandler decodes plain TON transfers: messages with empty body
+// or text comment, encrypted comments passed through as payload, and
+// bounced messages returning TON to sender.
+type tonTransferHandler struct{}
+
+func (h tonTransferHandler) Name() string {
+	return "ton_transfer"
+}
+
+func (h tonTransferHandler) Handle(_ context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	msgIn := tx.Msg
+	e := handler.Event{
+		Type:      storage.EventTypeTonTransfer,
+		Opcode:    tx.Opcode,
+		Sender:    msgIn.SrcAddr.String(),
+		Recipient: msgIn.DstAddr.String(),
+		Amount:    msgIn.Amount.Nano().String(),
+	}
+
+	body := msgIn.Body.BeginParse()
+	switch {
+	case msgIn.Bounced:
+		if tx.Opcode != structures.OpBounced {
+			return nil, nil
+		}
+		// body of bounced message starts with the body of the original one
+		e.Payload = base64.StdEncoding.EncodeToString(msgIn.Body.ToBOC())
+	case tx.Opcode == structures.OpEncryptedComment:
+		e.Payload = base64.StdEncoding.EncodeToString(msgIn.Body.ToBOC())
+	case tx.Opcode != structures.OpTextComment:
+		return nil, nil
+	case body.BitsLeft() == 0 && body.RefsNum() == 0:
+		// empty body
+	case body.BitsLeft() < 32:
+		return nil, nil
+	default:
+		_, _ = body.LoadUInt(32)
+		comment, err := body.LoadStringSnake()
+		if err != nil {
+			logrus.Debugf("[TON] failed to parse comment in tx %x: %s", tx.Tx.Hash, err)
+			e.Payload = base64.StdEncoding.EncodeToString(msgIn.Body.ToBOC())
+			break
+		}
+		e.Comment = comment
+	}
+
+	logrus.Debugf("[TON] %s from %s to %s", e.Amount, e.Sender, e.Recipient)
+
+	return []handler.Event{e}, nil
+}
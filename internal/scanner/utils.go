@@ -6,8 +6,9 @@ import (
 	"time"
 
 	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
-	"gorm.io/gorm"
 
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
 )
 
@@ -15,8 +16,30 @@ func (s *Scanner) getShardID(shard *ton.BlockIDExt) string {
 	return fmt.Sprintf("%d|%d", shard.Workchain, shard.Shard)
 }
 
+// collectShards returns shard blocks of master block to load transactions
+// from, keyed by workchain, shard and seqno.
+func (s *Scanner) collectShards(
+	ctx context.Context,
+	api *ton.APIClient,
+	currentShards []*ton.BlockIDExt,
+) (map[string]*ton.BlockIDExt, error) {
+	shards := make(map[string]*ton.BlockIDExt, len(currentShards))
+	for _, shard := range currentShards {
+		// unique key
+		key := fmt.Sprintf("%d:%d:%d", shard.Workchain, shard.Shard, shard.SeqNo)
+		shards[key] = shard
+
+		if err := s.fillWithNotSeenShards(ctx, api, shards, shard); err != nil {
+			return nil, err
+		}
+	}
+
+	return shards, nil
+}
+
 func (s *Scanner) fillWithNotSeenShards(
 	ctx context.Context,
+	api *ton.APIClient,
 	shards map[string]*ton.BlockIDExt,
 	shard *ton.BlockIDExt,
 ) error {
@@ -28,7 +51,7 @@ func (s *Scanner) fillWithNotSeenShards(
 
 	shards[key] = shard
 
-	block, err := s.api.GetBlockData(ctx, shard)
+	block, err := api.GetBlockData(ctx, shard)
 	if err != nil {
 		return fmt.Errorf("failed to get block data: %w", err)
 	}
@@ -39,7 +62,7 @@ func (s *Scanner) fillWithNotSeenShards(
 	}
 
 	for _, parent := range parents {
-		if err := s.fillWithNotSeenShards(ctx, shards, parent); err != nil {
+		if err := s.fillWithNotSeenShards(ctx, api, shards, parent); err != nil {
 			return err
 		}
 	}
@@ -47,15 +70,10 @@ func (s *Scanner) fillWithNotSeenShards(
 	return nil
 }
 
-func (s *Scanner) addBlock(master *ton.BlockIDExt, txDB *gorm.DB) error {
-	b := storage.Block{
-		SeqNo:       master.SeqNo,
-		Workchain:   master.Workchain,
-		Shard:       master.Shard,
-		ProcessedAt: time.Now(),
-	}
+func (s *Scanner) addBlock(ctx context.Context, master *ton.BlockIDExt, txCount int, events []storage.Event) error {
+	b := newBlock(master, txCount)
 
-	if err := txDB.Create(b).Error; err != nil {
+	if err := s.writer.push(ctx, blockBatch{block: b, events: events}); err != nil {
 		return err
 	}
 
@@ -65,6 +83,17 @@ func (s *Scanner) addBlock(master *ton.BlockIDExt, txDB *gorm.DB) error {
 	return nil
 }
 
+func newBlock(master *ton.BlockIDExt, txCount int) storage.Block {
+	count := uint32(txCount)
+	return storage.Block{
+		SeqNo:       master.SeqNo,
+		Workchain:   master.Workchain,
+		Shard:       master.Shard,
+		TxCount:     &count,
+		ProcessedAt: time.Now(),
+	}
+}
+
 func (s *Scanner) getLastBlockSeqno(ctx context.Context) (uint32, error) {
 	lastMaster, err := s.api.GetMasterchainInfo(ctx)
 	if err != nil {
@@ -73,3 +102,44 @@ func (s *Scanner) getLastBlockSeqno(ctx context.Context) (uint32, error) {
 
 	return lastMaster.SeqNo, nil
 }
+
+// isTxSuccess reports whether transaction compute and action phases succeeded.
+func isTxSuccess(tx *tlb.Transaction) bool {
+	desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok {
+		return true
+	}
+	if desc.Aborted {
+		return false
+	}
+	if compute, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseVM); ok && !compute.Success {
+		return false
+	}
+	if desc.ActionPhase != nil && !desc.ActionPhase.Success {
+		return false
+	}
+
+	return true
+}
+
+// codeHash returns code hash of contract, cached because code upgrades
+// are rare. The cache is bounded and cleared by metadata_refresh job.
+func (s *Scanner) codeHash(ctx context.Context, master *ton.BlockIDExt, addr *address.Address) ([]byte, error) {
+	key := addr.String()
+	if hash, ok := s.codeHashes.Load(key); ok {
+		return hash, nil
+	}
+
+	acc, err := s.blockAPI(master.SeqNo).GetAccount(ctx, master, addr)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.IsActive || acc.Code == nil {
+		return nil, nil
+	}
+
+	hash := acc.Code.Hash()
+	s.codeHashes.Store(key, hash)
+
+	return hash, nil
+}
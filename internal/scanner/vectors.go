@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/testvectors"
+)
+
+// CaptureVector fetches transaction of account and returns test vector
+// of its incoming message with events decoded by built-in handlers.
+// Chain state is taken from the last master block, fields depending on
+// it are left empty, so the vector can be checked without liteserver.
+func (s *Scanner) CaptureVector(ctx context.Context, addr *address.Address, lt uint64, hash []byte) (testvectors.Vector, error) {
+	txs, err := s.api.ListTransactions(ctx, addr, 1, lt, hash)
+	if err != nil {
+		return testvectors.Vector{}, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if len(txs) == 0 {
+		return testvectors.Vector{}, fmt.Errorf("transaction %d of %s not found", lt, addr)
+	}
+	tx := txs[0]
+	if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return testvectors.Vector{}, fmt.Errorf("transaction has no incoming internal message")
+	}
+	msgIn := tx.IO.In.AsInternal()
+	if msgIn.Body == nil {
+		return testvectors.Vector{}, fmt.Errorf("incoming message has no body")
+	}
+
+	master, err := s.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return testvectors.Vector{}, fmt.Errorf("failed to get master block: %w", err)
+	}
+	// vectors are replayed without liteserver, so body is stored resolved
+	body, err := handler.ResolveCell(ctx, s.api, msgIn.Body)
+	if err != nil {
+		return testvectors.Vector{}, fmt.Errorf("failed to resolve message body: %w", err)
+	}
+	if body != msgIn.Body {
+		msg := *msgIn
+		msg.Body = body
+		msgIn = &msg
+	}
+
+	htx := &handler.Tx{
+		Master: master,
+		API:    s.api,
+		Tx:     tx,
+		Msg:    msgIn,
+	}
+	if op, err := body.BeginParse().LoadUInt(32); err == nil {
+		htx.Opcode = uint32(op)
+	}
+
+	var events []handler.Event
+	for _, h := range s.handlers.Handlers(htx.Opcode, nil) {
+		decoded, err := s.metrics.call(ctx, h, htx)
+		if err != nil {
+			return testvectors.Vector{}, fmt.Errorf("handler %s failed: %w", h.Name(), err)
+		}
+		for _, e := range decoded {
+			e.JettonMaster, e.JettonWalletCode, e.JettonWalletType = "", "", ""
+			events = append(events, e)
+		}
+	}
+
+	return testvectors.Vector{
+		TxHash:  hex.EncodeToString(tx.Hash),
+		Src:     msgIn.SrcAddr.String(),
+		Dst:     msgIn.DstAddr.String(),
+		Bounced: msgIn.Bounced,
+		Amount:  msgIn.Amount.Nano().String(),
+		Body:    base64.StdEncoding.EncodeToString(body.ToBOC()),
+		Events:  events,
+	}, nil
+}
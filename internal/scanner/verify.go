@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xssnick/tonutils-go/ton"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// stored blocks are loaded by chunks of seqnos
+const verifyChunkSize = 1000
+
+type TxCountMismatch struct {
+	SeqNo  uint32
+	Stored uint32
+	Actual uint32
+}
+
+// VerifyReport is a result of cross-check of stored blocks with liteservers.
+type VerifyReport struct {
+	Checked    int
+	Missing    []uint32
+	Mismatches []TxCountMismatch
+	// blocks stored before transactions were counted
+	Unverified []uint32
+}
+
+func (r *VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatches) == 0
+}
+
+// Verify checks that sampled master blocks in [from, to] are stored and
+// their transaction counts match liteserver data. All blocks are checked
+// if sample is zero or exceeds the range.
+func (s *Scanner) Verify(ctx context.Context, from, to uint32, sample int) (*VerifyReport, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid range [%d, %d]", from, to)
+	}
+
+	seqnos := sampleRange(from, to, sample)
+
+	stored := make(map[uint32]storage.Block, len(seqnos))
+	for i := 0; i < len(seqnos); i += verifyChunkSize {
+		var blocks []storage.Block
+		chunk := seqnos[i:min(i+verifyChunkSize, len(seqnos))]
+		if err := app.DB.Where("seq_no IN ?", chunk).Find(&blocks).Error; err != nil {
+			return nil, err
+		}
+		for _, b := range blocks {
+			stored[b.SeqNo] = b
+		}
+	}
+
+	report := &VerifyReport{}
+	for _, seqno := range seqnos {
+		report.Checked++
+
+		b, ok := stored[seqno]
+		if !ok {
+			report.Missing = append(report.Missing, seqno)
+			continue
+		}
+		if b.TxCount == nil {
+			report.Unverified = append(report.Unverified, seqno)
+			continue
+		}
+
+		actual, err := s.countTxs(ctx, seqno)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count transactions of block %d: %w", seqno, err)
+		}
+		if actual != *b.TxCount {
+			report.Mismatches = append(report.Mismatches, TxCountMismatch{
+				SeqNo:  seqno,
+				Stored: *b.TxCount,
+				Actual: actual,
+			})
+		}
+		logrus.Debugf("[VRF] block %d: stored %d, actual %d transactions", seqno, *b.TxCount, actual)
+	}
+
+	return report, nil
+}
+
+// countTxs counts transactions of shard blocks of master block
+// the same way they are collected during scanning. Shards are loaded
+// from liteserver, bypassing block cache.
+func (s *Scanner) countTxs(ctx context.Context, seqno uint32) (uint32, error) {
+	master, err := s.lookupMaster(ctx, seqno)
+	if err != nil {
+		return 0, err
+	}
+	api := s.blockAPI(seqno)
+
+	currentShards, err := api.GetBlockShardsInfo(ctx, master)
+	if err != nil {
+		return 0, err
+	}
+	shards, err := s.collectShards(ctx, api, currentShards)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint32
+	for _, shard := range shards {
+		var (
+			after *ton.TransactionID3
+			more  = true
+			txs   []ton.TransactionShortInfo
+		)
+		for more {
+			txs, more, err = api.GetBlockTransactionsV2(ctx, shard, 100, after)
+			if err != nil {
+				return 0, err
+			}
+			if more {
+				after = txs[len(txs)-1].ID3()
+			}
+			count += uint32(len(txs))
+		}
+	}
+
+	return count, nil
+}
+
+func sampleRange(from, to uint32, sample int) []uint32 {
+	size := int(to-from) + 1
+	if sample <= 0 || sample >= size {
+		seqnos := make([]uint32, 0, size)
+		for seqno := from; seqno <= to && seqno >= from; seqno++ {
+			seqnos = append(seqnos, seqno)
+		}
+		return seqnos
+	}
+
+	picked := make(map[uint32]struct{}, sample)
+	for len(picked) < sample {
+		picked[from+uint32(rand.IntN(size))] = struct{}{}
+	}
+	seqnos := make([]uint32, 0, sample)
+	for seqno := range picked {
+		seqnos = append(seqnos, seqno)
+	}
+	slices.Sort(seqnos)
+
+	return seqnos
+}
@@ -0,0 +1,565 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	writerQueueSize     = 64
+	writerBatchSize     = 1000
+	writerFlushInterval = time.Second
+	writerRetryDelay    = 2 * time.Second
+)
+
+// blockBatch is a processed master block together with its decoded events.
+type blockBatch struct {
+	block  storage.Block
+	events []storage.Event
+	// block was stored before and its events should be superseded
+	reparse bool
+	// events of historical block found by account backfill,
+	// the block itself is not stored
+	backfill bool
+	// hashes of backfilled transactions, recorded so they aren't indexed again
+	txs []string
+	// receives result of storing the block if set, must be buffered
+	stored chan<- error
+}
+
+// writer persists processed blocks in a dedicated goroutine. Batches of
+// several master blocks are buffered, their events sorted by (block, lt)
+// and inserted with bulk statements in a single db transaction, so the
+// scanner doesn't wait for the database between blocks. Writers of
+// standalone tools have no broker and store corrections instead.
+type writer struct {
+	broker    *stream.Broker
+	stats     *stats
+	compactor atomic.Pointer[compactor]
+	dedup     *notifyDedup
+	in        chan blockBatch
+	quit      chan struct{}
+	done      chan struct{}
+	// canceled when stop deadline passes, pending blocks are abandoned
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// blocks pushed but not stored yet
+	pending       atomic.Int64
+	lastCommitted atomic.Uint32
+}
+
+func newWriter(broker *stream.Broker, st *stats, c *compactor) *writer {
+	w := &writer{
+		broker: broker,
+		stats:  st,
+		in:     make(chan blockBatch, writerQueueSize),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.compactor.Store(c)
+
+	return w
+}
+
+func (w *writer) push(ctx context.Context, b blockBatch) error {
+	w.pending.Add(1)
+	select {
+	case w.in <- b:
+		return nil
+	case <-ctx.Done():
+		w.pending.Add(-1)
+		return ctx.Err()
+	}
+}
+
+func (w *writer) run() {
+	defer close(w.done)
+
+	var (
+		pending []blockBatch
+		events  int
+		ticker  = time.NewTicker(writerFlushInterval)
+	)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		w.flush(pending)
+		pending = nil
+		events = 0
+	}
+
+	for {
+		select {
+		case b := <-w.in:
+			pending = append(pending, b)
+			events += len(b.events)
+			// caller waiting for the block isn't held for the interval
+			if events >= writerBatchSize || b.stored != nil {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-w.quit:
+			for {
+				select {
+				case b := <-w.in:
+					pending = append(pending, b)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// stop flushes everything queued so far and waits for the writer to exit.
+// When ctx is done, blocks which aren't stored yet are abandoned and
+// stay uncommitted, scanning resumes from them after restart.
+func (w *writer) stop(ctx context.Context) {
+	close(w.quit)
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+	}
+}
+
+// db returns db handle which is canceled with the writer.
+func (w *writer) db() *gorm.DB {
+	return app.DB.WithContext(w.ctx)
+}
+
+// flush retries transient errors until the batch is stored, blocks must
+// not be lost because the scanner has already moved past them. Batch
+// failing with a permanent error is split in halves to isolate the
+// poison block, which is dead-lettered and left to gap filling. Batches
+// of stopped writer are abandoned instead.
+func (w *writer) flush(batches []blockBatch) {
+	for {
+		err := w.store(batches)
+		if err == nil {
+			for _, b := range batches {
+				if b.stored != nil {
+					b.stored <- nil
+				}
+			}
+			return
+		}
+		if w.ctx.Err() != nil {
+			w.abandon(batches, err)
+			return
+		}
+		if !isTransientDBError(err) {
+			logrus.Errorf("[WRT] failed to store [%d] blocks: %s", len(batches), err)
+			if len(batches) == 1 {
+				w.deadLetter(batches[0], err)
+				return
+			}
+			mid := len(batches) / 2
+			w.flush(batches[:mid])
+			w.flush(batches[mid:])
+			return
+		}
+
+		logrus.Errorf("[WRT] failed to store [%d] blocks, retrying: %s", len(batches), err)
+		select {
+		case <-w.ctx.Done():
+			w.abandon(batches, err)
+			return
+		case <-time.After(writerRetryDelay):
+		}
+	}
+}
+
+// abandon gives up batches on shutdown. They are left pending, so they
+// are reported as uncommitted.
+func (w *writer) abandon(batches []blockBatch, err error) {
+	logrus.Errorf("[WRT] writer stopped, [%d] blocks are not stored: %s", len(batches), err)
+	for _, b := range batches {
+		if b.stored != nil {
+			b.stored <- err
+		}
+	}
+}
+
+// store inserts batches and publishes them. Events are copied, so
+// failed attempt doesn't leave ids or indexes on batches.
+func (w *writer) store(batches []blockBatch) error {
+	var (
+		blocks     = make([]storage.Block, 0, len(batches))
+		events     []storage.Event
+		reparsed   = make(map[uint32]struct{})
+		backfilled = make(map[uint32]struct{})
+		indexed    []storage.IndexedTx
+		head       uint32
+	)
+	for _, b := range batches {
+		events = append(events, b.events...)
+		if b.backfill {
+			backfilled[b.block.SeqNo] = struct{}{}
+			for _, h := range b.txs {
+				indexed = append(indexed, storage.IndexedTx{TxHash: h, SeqNo: b.block.SeqNo})
+			}
+			continue
+		}
+		blocks = append(blocks, b.block)
+		if b.reparse {
+			reparsed[b.block.SeqNo] = struct{}{}
+			continue
+		}
+		head = max(head, b.block.SeqNo)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].SeqNo != events[j].SeqNo {
+			return events[i].SeqNo < events[j].SeqNo
+		}
+		return events[i].LT < events[j].LT
+	})
+	if w.dedup != nil {
+		var err error
+		events, err = w.dedup.filter(w.db(), events, reparsed, w.stats)
+		if err != nil {
+			return err
+		}
+	}
+	// events of addresses purged meanwhile aren't stored again
+	suppressed, err := storage.SuppressedAddresses(w.db())
+	if err != nil {
+		return err
+	}
+	var dropped int
+	if events, dropped = storage.FilterSuppressed(events, suppressed); dropped > 0 {
+		logrus.Debugf("[WRT] dropped [%d] events of purged addresses", dropped)
+	}
+	var summaries []storage.EventSummary
+	if c := w.compactor.Load(); c != nil {
+		events, summaries = c.compact(events)
+	}
+
+	start := time.Now()
+	superseded, err := w.insert(blocks, events, summaries, indexed, reparsed, backfilled)
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("[WRT] stored [%d] blocks with [%d] events in [%.3fs]",
+		len(blocks), len(events), time.Since(start).Seconds())
+	w.stats.addBlocks(blocks, len(events))
+	w.pending.Add(-int64(len(batches)))
+	if head > w.lastCommitted.Load() {
+		w.lastCommitted.Store(head)
+	}
+	if w.broker != nil {
+		w.broker.Publish(newStreamBatch(head, events, superseded, corrected(reparsed, backfilled)))
+	}
+
+	return nil
+}
+
+// corrected returns blocks which subscribers get corrections of.
+func corrected(reparsed, backfilled map[uint32]struct{}) map[uint32]struct{} {
+	seqnos := make(map[uint32]struct{}, len(reparsed)+len(backfilled))
+	for seqno := range reparsed {
+		seqnos[seqno] = struct{}{}
+	}
+	for seqno := range backfilled {
+		seqnos[seqno] = struct{}{}
+	}
+
+	return seqnos
+}
+
+// deadLetter drops batch which can't be stored. The block stays missing,
+// so gap filling picks it up again after it's fixed.
+func (w *writer) deadLetter(b blockBatch, err error) {
+	logrus.Errorf("[WRT] dropping block [%d] with [%d] events: %s", b.block.SeqNo, len(b.events), err)
+	recordFailed(b.block.SeqNo, err)
+	w.pending.Add(-1)
+	if b.stored != nil {
+		b.stored <- err
+	}
+}
+
+// isTransientDBError reports errors which retry of the same statements
+// can fix: lost connections, serialization failures, lack of resources
+// or shutting down server. Errors of values and constraints are permanent.
+func isTransientDBError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		// network and driver errors
+		return true
+	}
+	switch pgErr.Code[:2] {
+	case "08", "40", "53", "57", "58":
+		return true
+	}
+
+	return false
+}
+
+// insert stores blocks, events and summaries in one db transaction. Events
+// of reparsed blocks stored before are soft deleted and returned, new ones
+// get the next revision. Summaries of reparsed blocks are replaced.
+// Events of backfilled blocks are indexed after events stored before,
+// their transactions are recorded as indexed.
+// Writer without broker stores corrections for running scanner to publish.
+func (w *writer) insert(
+	blocks []storage.Block,
+	events []storage.Event,
+	summaries []storage.EventSummary,
+	indexed []storage.IndexedTx,
+	reparsed map[uint32]struct{},
+	backfilled map[uint32]struct{},
+) ([]storage.Event, error) {
+	var superseded []storage.Event
+
+	txDB := w.db().Begin()
+	if err := assignIndexes(txDB, events, backfilled); err != nil {
+		txDB.Rollback()
+		return nil, err
+	}
+	if len(reparsed) > 0 {
+		var err error
+		superseded, err = supersedeEvents(txDB, reparsed, events)
+		if err != nil {
+			txDB.Rollback()
+			return nil, err
+		}
+	}
+	if len(events) > 0 {
+		if err := txDB.CreateInBatches(events, writerBatchSize).Error; err != nil {
+			txDB.Rollback()
+			return nil, err
+		}
+	}
+	if err := insertSummaries(txDB, summaries, reparsed); err != nil {
+		txDB.Rollback()
+		return nil, err
+	}
+	if len(indexed) > 0 {
+		err := txDB.Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(indexed, writerBatchSize).Error
+		if err != nil {
+			txDB.Rollback()
+			return nil, err
+		}
+	}
+	// reparsed blocks are already stored
+	if len(blocks) > 0 {
+		if err := txDB.Clauses(clause.OnConflict{UpdateAll: true}).Create(blocks).Error; err != nil {
+			txDB.Rollback()
+			return nil, err
+		}
+	}
+	if w.broker == nil {
+		pending := pendingCorrections(events, superseded, corrected(reparsed, backfilled))
+		if len(pending) > 0 {
+			if err := txDB.Create(pending).Error; err != nil {
+				txDB.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := txDB.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return superseded, nil
+}
+
+func supersedeEvents(
+	txDB *gorm.DB,
+	reparsed map[uint32]struct{},
+	events []storage.Event,
+) ([]storage.Event, error) {
+	seqnos := make([]uint32, 0, len(reparsed))
+	for seqno := range reparsed {
+		seqnos = append(seqnos, seqno)
+	}
+
+	var revisions []struct {
+		SeqNo    uint32
+		Revision uint32
+	}
+	err := txDB.Unscoped().
+		Model(&storage.Event{}).
+		Select("seq_no, max(revision) + 1 AS revision").
+		Where("seq_no IN ?", seqnos).
+		Group("seq_no").
+		Scan(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	next := make(map[uint32]uint32, len(revisions))
+	for _, r := range revisions {
+		next[r.SeqNo] = r.Revision
+	}
+	for i := range events {
+		if _, ok := reparsed[events[i].SeqNo]; ok {
+			events[i].Revision = next[events[i].SeqNo]
+		}
+	}
+
+	var superseded []storage.Event
+	if err := txDB.Where("seq_no IN ?", seqnos).Find(&superseded).Error; err != nil {
+		return nil, err
+	}
+	if len(superseded) == 0 {
+		return nil, nil
+	}
+
+	deletedAt := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	err = txDB.Model(&storage.Event{}).
+		Where("seq_no IN ?", seqnos).
+		Update("deleted_at", deletedAt).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range superseded {
+		superseded[i].DeletedAt = deletedAt
+	}
+
+	return superseded, nil
+}
+
+// assignIndexes numbers sorted events within their blocks. Events of
+// backfilled blocks continue after already stored ones, so resume
+// tokens stay unique.
+func assignIndexes(txDB *gorm.DB, events []storage.Event, backfilled map[uint32]struct{}) error {
+	next := make(map[uint32]uint32)
+	if len(backfilled) > 0 {
+		seqnos := make([]uint32, 0, len(backfilled))
+		for seqno := range backfilled {
+			seqnos = append(seqnos, seqno)
+		}
+		var stored []struct {
+			SeqNo      uint32
+			EventIndex uint32
+		}
+		err := txDB.Unscoped().
+			Model(&storage.Event{}).
+			Select("seq_no, max(event_index) + 1 AS event_index").
+			Where("seq_no IN ?", seqnos).
+			Group("seq_no").
+			Scan(&stored).Error
+		if err != nil {
+			return err
+		}
+		for _, s := range stored {
+			next[s.SeqNo] = s.EventIndex
+		}
+	}
+
+	for i := range events {
+		if i > 0 && events[i].SeqNo == events[i-1].SeqNo {
+			events[i].EventIndex = events[i-1].EventIndex + 1
+			continue
+		}
+		events[i].EventIndex = next[events[i].SeqNo]
+	}
+
+	return nil
+}
+
+func insertSummaries(txDB *gorm.DB, summaries []storage.EventSummary, reparsed map[uint32]struct{}) error {
+	if len(reparsed) > 0 {
+		seqnos := make([]uint32, 0, len(reparsed))
+		for seqno := range reparsed {
+			seqnos = append(seqnos, seqno)
+		}
+		if err := txDB.Where("seq_no IN ?", seqnos).Delete(&storage.EventSummary{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	return txDB.CreateInBatches(summaries, writerBatchSize).Error
+}
+
+// pendingCorrections refers stored events of corrected blocks by ids.
+func pendingCorrections(events, superseded []storage.Event, seqnos map[uint32]struct{}) []storage.PendingCorrection {
+	byBlock := make(map[uint32]*storage.PendingCorrection, len(seqnos))
+	for seqno := range seqnos {
+		byBlock[seqno] = &storage.PendingCorrection{SeqNo: seqno}
+	}
+	for _, e := range events {
+		if c, ok := byBlock[e.SeqNo]; ok {
+			c.EventIDs = append(c.EventIDs, e.ID)
+		}
+	}
+	for _, e := range superseded {
+		if c, ok := byBlock[e.SeqNo]; ok {
+			c.SupersededIDs = append(c.SupersededIDs, e.ID)
+		}
+	}
+
+	pending := make([]storage.PendingCorrection, 0, len(byBlock))
+	for _, c := range byBlock {
+		pending = append(pending, *c)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].SeqNo < pending[j].SeqNo
+	})
+
+	return pending
+}
+
+// newStreamBatch splits stored events into live ones and corrections of reparsed blocks.
+func newStreamBatch(
+	head uint32,
+	events []storage.Event,
+	superseded []storage.Event,
+	reparsed map[uint32]struct{},
+) stream.Batch {
+	batch := stream.Batch{Head: head}
+	if len(reparsed) == 0 {
+		batch.Events = events
+		return batch
+	}
+
+	corrections := make(map[uint32]*stream.Correction, len(reparsed))
+	for seqno := range reparsed {
+		corrections[seqno] = &stream.Correction{SeqNo: seqno}
+	}
+	for _, e := range events {
+		if c, ok := corrections[e.SeqNo]; ok {
+			c.Events = append(c.Events, e)
+			continue
+		}
+		batch.Events = append(batch.Events, e)
+	}
+	for _, e := range superseded {
+		c := corrections[e.SeqNo]
+		c.Superseded = append(c.Superseded, e)
+	}
+
+	for _, c := range corrections {
+		batch.Corrections = append(batch.Corrections, *c)
+	}
+	sort.Slice(batch.Corrections, func(i, j int) bool {
+		return batch.Corrections[i].SeqNo < batch.Corrections[j].SeqNo
+	})
+
+	return batch
+}
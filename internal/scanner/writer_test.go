@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+// Writer benchmarks need a scratch database, its tables are truncated:
+//
+//	BENCH_POSTGRES_DSN="host=localhost user=postgres dbname=bench sslmode=disable" \
+//		go test -run - -bench Insert ./internal/scanner
+const (
+	benchBlocks = 8
+	// events of a heavy master block
+	benchEvents = 2000
+)
+
+func benchDB(b *testing.B) {
+	dsn := os.Getenv("BENCH_POSTGRES_DSN")
+	if dsn == "" {
+		b.Skip("BENCH_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := db.AutoMigrate(storage.Models()...); err != nil {
+		b.Fatal(err)
+	}
+	app.DB = db
+	benchTruncate(b)
+}
+
+func benchTruncate(b *testing.B) {
+	if err := app.DB.Exec("TRUNCATE blocks, events, event_summaries").Error; err != nil {
+		b.Fatal(err)
+	}
+}
+
+func benchBatches(round int) []blockBatch {
+	batches := make([]blockBatch, benchBlocks)
+	for i := range batches {
+		seqno := uint32(round*benchBlocks + i + 1)
+		events := make([]storage.Event, benchEvents)
+		for j := range events {
+			events[j] = storage.Event{
+				Type:      storage.EventTypeJettonTransfer,
+				SeqNo:     seqno,
+				LT:        uint64(benchEvents - j),
+				TxHash:    fmt.Sprintf("%064x", j),
+				Opcode:    0x7362d09c,
+				Sender:    "EQAKNnuSzwsDff2Jlg7oMtVvf8FRaBu0HlNpDndvV4aZimBb",
+				Recipient: "EQBmXQaY28j7la_CXDpNnPKA2HpYW3mZJDymAI_QMliXX-IG",
+				Amount:    "1000000",
+				Success:   true,
+			}
+		}
+		batches[i] = blockBatch{
+			block:  storage.Block{SeqNo: seqno, ProcessedAt: time.Now()},
+			events: events,
+		}
+	}
+
+	return batches
+}
+
+// BenchmarkInsertPerBlock stores every block in its own transaction with
+// a statement per event, as blocks were stored before the writer.
+func BenchmarkInsertPerBlock(b *testing.B) {
+	benchDB(b)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		batches := benchBatches(i)
+		b.StartTimer()
+
+		for _, batch := range batches {
+			txDB := app.DB.Begin()
+			for j := range batch.events {
+				if err := txDB.Create(&batch.events[j]).Error; err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := txDB.Create(&batch.block).Error; err != nil {
+				b.Fatal(err)
+			}
+			if err := txDB.Commit().Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkInsertWriter stores the same blocks with a single writer flush.
+func BenchmarkInsertWriter(b *testing.B) {
+	benchDB(b)
+	w := newWriter(stream.NewBroker(), newStats(), nil)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		batches := benchBatches(i)
+		w.pending.Add(int64(len(batches)))
+		b.StartTimer()
+
+		if err := w.store(batches); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package scheduler runs periodic background jobs of the scanner.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jitter is a fraction of interval runs are randomly shifted by,
+// so jobs of several processes don't hit the database at once.
+const jitter = 0.1
+
+// JobStatus describes job runs since start.
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Interval     time.Duration `json:"interval_ns"`
+	Enabled      bool          `json:"enabled"`
+	Running      bool          `json:"running"`
+	Runs         uint64        `json:"runs"`
+	Failures     uint64        `json:"failures"`
+	LastStart    time.Time     `json:"last_start,omitempty"`
+	LastDuration time.Duration `json:"last_duration_ns"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run,omitempty"`
+}
+
+type job struct {
+	fn func(ctx context.Context) error
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// Scheduler runs every job in its own goroutine. The next run of a job
+// is scheduled after the previous one completes, so runs of the same
+// job never overlap however long they take.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*job)}
+}
+
+// Add registers job, it's disabled if interval is not positive.
+// Must be called before Run.
+func (s *Scheduler) Add(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[name] = &job{
+		fn: fn,
+		status: JobStatus{
+			Name:     name,
+			Interval: interval,
+			Enabled:  interval > 0,
+		},
+	}
+}
+
+// Disable turns off registered jobs, unknown names are reported.
+func (s *Scheduler) Disable(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		j, ok := s.jobs[name]
+		if !ok {
+			logrus.Warnf("[SCH] can't disable unknown job %s", name)
+			continue
+		}
+		j.status.Enabled = false
+	}
+}
+
+// Run starts enabled jobs, they stop when ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		if j.status.Enabled {
+			go j.run(ctx)
+		}
+	}
+}
+
+// Status returns state of all jobs ordered by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		statuses = append(statuses, j.status)
+		j.mu.Unlock()
+	}
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].Name < statuses[k].Name
+	})
+
+	return statuses
+}
+
+func (j *job) run(ctx context.Context) {
+	for {
+		delay := withJitter(j.status.Interval)
+		j.mu.Lock()
+		j.status.NextRun = time.Now().Add(delay)
+		j.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		j.runOnce(ctx)
+	}
+}
+
+func (j *job) runOnce(ctx context.Context) {
+	start := time.Now()
+	j.mu.Lock()
+	j.status.Running = true
+	j.status.LastStart = start
+	j.status.NextRun = time.Time{}
+	j.mu.Unlock()
+
+	err := j.call(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastDuration = time.Since(start)
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		logrus.Errorf("[SCH] job %s failed: %s", j.status.Name, err)
+	}
+}
+
+// call runs job function, recovering its panics.
+func (j *job) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return j.fn(ctx)
+}
+
+func withJitter(d time.Duration) time.Duration {
+	spread := time.Duration(float64(d) * jitter)
+	if spread <= 0 {
+		return d
+	}
+
+	return d - spread + rand.N(2*spread)
+}
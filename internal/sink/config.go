@@ -0,0 +1,155 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/xssnick/tonutils-go/address"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/fieldcrypt"
+)
+
+// Config describes sinks and routing rules, rules are checked in order
+// and the first matching one wins, unmatched events go to default sinks:
+//
+//	{
+//	  "sinks": {
+//	    "payments": {"type": "webhook", "url": "https://...", "secret": "enc:v2:...", "max_attempts": 50},
+//	    "analytics": {"type": "webhook", "url": "https://...", "encoding": "protobuf"}
+//	  },
+//	  "routes": [
+//	    {"jetton_master": "EQ...", "type": "jetton_transfer", "sinks": ["payments"]}
+//	  ],
+//	  "default": ["analytics"]
+//	}
+type Config struct {
+	Sinks   map[string]SinkConfig `json:"sinks"`
+	Routes  []RouteConfig         `json:"routes"`
+	Default []string              `json:"default"`
+}
+
+// SinkConfig describes sink, webhook encoding is json (default)
+// or protobuf, see proto/events.proto. Secret can be encrypted
+// with cmd/encrypt, it's decrypted on load. In outbox mode job is
+// dead-lettered after MaxAttempts failed deliveries (defaults to
+// defaultMaxAttempts), so it doesn't block later jobs of the sink.
+type SinkConfig struct {
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	Secret      string `json:"secret"`
+	Encoding    string `json:"encoding"`
+	MaxAttempts uint32 `json:"max_attempts"`
+}
+
+// SecretScope binds encrypted secrets to sink config.
+const SecretScope = "sink.secret"
+
+// RouteConfig matches events by attributes, empty attributes match any value.
+type RouteConfig struct {
+	JettonMaster string   `json:"jetton_master"`
+	Type         string   `json:"type"`
+	Sinks        []string `json:"sinks"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse routes config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	for name, s := range cfg.Sinks {
+		s.Secret, err = fieldcrypt.Decrypt(s.Secret, SecretScope)
+		if err != nil {
+			return nil, fmt.Errorf("sink %s: failed to decrypt secret: %w", name, err)
+		}
+		cfg.Sinks[name] = s
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	for name, s := range c.Sinks {
+		switch s.Type {
+		case TypeWebhook:
+			if s.URL == "" {
+				return fmt.Errorf("sink %s: url is required", name)
+			}
+			switch s.Encoding {
+			case "", EncodingJSON, EncodingProtobuf:
+			default:
+				return fmt.Errorf("sink %s: unknown encoding %q", name, s.Encoding)
+			}
+		case TypeLog:
+		default:
+			return fmt.Errorf("sink %s: unknown type %q", name, s.Type)
+		}
+	}
+
+	for i, r := range c.Routes {
+		if len(r.Sinks) == 0 {
+			return fmt.Errorf("route %d: no sinks", i)
+		}
+		if err := c.checkSinks(r.Sinks); err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+		if r.JettonMaster != "" {
+			if _, err := parseAddr(r.JettonMaster); err != nil {
+				return fmt.Errorf("route %d: invalid jetton master: %w", i, err)
+			}
+		}
+	}
+
+	return c.checkSinks(c.Default)
+}
+
+func (c *Config) checkSinks(names []string) error {
+	for _, name := range names {
+		if _, ok := c.Sinks[name]; !ok {
+			return fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	return nil
+}
+
+// parseAddr accepts both user-friendly and raw address forms.
+func parseAddr(s string) (*address.Address, error) {
+	addr, err := address.ParseAddr(s)
+	if err == nil {
+		return addr, nil
+	}
+	return address.ParseRawAddr(s)
+}
+
+func rawString(addr *address.Address) string {
+	return fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())
+}
+
+// Ping checks that sink is reachable without delivering anything,
+// any http response of webhook endpoint is fine.
+func (s SinkConfig) Ping(ctx context.Context) error {
+	if s.Type != TypeWebhook {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: webhookTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
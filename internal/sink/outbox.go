@@ -0,0 +1,232 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	workerPollInterval = time.Second
+	defaultMaxAttempts = 20
+	jobLease           = time.Minute
+)
+
+// enqueue stores payloads as sink jobs and moves cursors of all sinks in
+// one db transaction, so restart never enqueues the same events twice or
+// skips them. It retries until they are stored and returns false if ctx
+// is done.
+func (r *Router) enqueue(ctx context.Context, payloads map[*queue]*Payload, d delivery) bool {
+	if len(payloads) == 0 && !d.hasThrough {
+		return true
+	}
+
+	jobs := make([]storage.SinkJob, 0, len(payloads))
+	now := time.Now()
+	for q, p := range payloads {
+		data, err := json.Marshal(p)
+		if err != nil {
+			// payloads are plain structs
+			logrus.Errorf("[SNK] failed to encode payload for %s: %s", q.sink.Name(), err)
+			continue
+		}
+		jobs = append(jobs, storage.SinkJob{
+			Sink:          q.sink.Name(),
+			Payload:       storage.EncryptedString(data),
+			AddressHashes: storage.EventAddressHashes(p.allEvents()),
+			NextAttemptAt: now,
+		})
+	}
+
+	var cursors []storage.SinkCursor
+	if d.hasThrough {
+		for name, q := range r.queues {
+			if !q.behind(d.through) {
+				continue
+			}
+			cursors = append(cursors, storage.SinkCursor{
+				Sink:       name,
+				SeqNo:      d.through.SeqNo,
+				EventIndex: d.through.Index,
+			})
+		}
+	}
+
+	delay := retryDelayBase
+	for {
+		err := app.DB.Transaction(func(tx *gorm.DB) error {
+			if len(jobs) > 0 {
+				if err := tx.Create(&jobs).Error; err != nil {
+					return err
+				}
+			}
+			return storage.SaveSinkCursors(tx, cursors)
+		})
+		if err == nil {
+			return true
+		}
+		logrus.Errorf("[SNK] failed to store sink jobs: %s", err)
+		for i := range jobs {
+			jobs[i].ID = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, retryDelayMax)
+	}
+}
+
+func (r *Router) outboxReport() []QueueReport {
+	queues, err := storage.CountSinkJobs(app.DB)
+	if err != nil {
+		logrus.Errorf("[SNK] failed to count sink jobs: %s", err)
+	}
+	counts := make(map[string]storage.SinkQueue, len(queues))
+	for _, q := range queues {
+		counts[q.Sink] = q
+	}
+
+	reports := make([]QueueReport, 0, len(r.queues))
+	for name := range r.queues {
+		reports = append(reports, QueueReport{
+			Sink:   name,
+			Queued: int(counts[name].Jobs),
+			Dead:   int(counts[name].Dead),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Sink < reports[j].Sink
+	})
+
+	return reports
+}
+
+// Worker delivers sink jobs stored by router in outbox mode. Any number
+// of workers can run in separate processes, jobs of one sink are
+// delivered by one worker at a time in order.
+type Worker struct {
+	sinks       []Sink
+	maxAttempts map[string]uint32
+}
+
+// NewWorker returns worker of named sinks, all configured sinks if names is empty.
+func NewWorker(cfg *Config, names []string) (*Worker, error) {
+	w := &Worker{maxAttempts: make(map[string]uint32)}
+	if len(names) == 0 {
+		for name := range cfg.Sinks {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		sc, ok := cfg.Sinks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sink %q", name)
+		}
+		w.sinks = append(w.sinks, newSink(name, sc))
+		w.maxAttempts[name] = sc.MaxAttempts
+		if sc.MaxAttempts == 0 {
+			w.maxAttempts[name] = defaultMaxAttempts
+		}
+	}
+
+	return w, nil
+}
+
+// Run delivers jobs until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	logrus.Infof("[SNK] worker started for %d sinks", len(w.sinks))
+	for {
+		delivered := 0
+		for _, s := range w.sinks {
+			if ctx.Err() != nil {
+				return
+			}
+			ok, err := w.deliverNext(ctx, s)
+			if err != nil {
+				logrus.Errorf("[SNK] failed to process job of %s: %s", s.Name(), err)
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if delivered > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(workerPollInterval):
+		}
+	}
+}
+
+// deliverNext sends the oldest due job of sink. The job is leased in one
+// short db transaction and, after delivery, deleted on success or
+// postponed with backoff on failure in another one, so no transaction is
+// held while sink is slow. Jobs which can't be decoded or failed max
+// attempts are dead-lettered. It returns true if job was delivered.
+func (w *Worker) deliverNext(ctx context.Context, s Sink) (bool, error) {
+	db := app.DB.WithContext(ctx)
+	job, err := storage.ClaimSinkJob(db, s.Name(), jobLease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	data, err := storage.SinkJobPayload(db, job)
+	if errors.Is(err, storage.ErrBrokenPayload) {
+		return false, killJob(db, s, job, err)
+	}
+	if err != nil {
+		return false, err
+	}
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return false, killJob(db, s, job, fmt.Errorf("%w: %w", storage.ErrBrokenPayload, err))
+	}
+
+	// delivery must end before the lease, so job isn't sent twice at once
+	sendCtx, cancel := context.WithTimeout(ctx, jobLease/2)
+	sendErr := s.Send(sendCtx, p)
+	cancel()
+	if sendErr == nil {
+		return true, storage.CompleteSinkJob(db, job)
+	}
+	logrus.Errorf("[SNK] failed to deliver job %d to %s: %s", job.ID, s.Name(), sendErr)
+	job.Attempts++
+	if job.Attempts >= w.maxAttempts[s.Name()] {
+		return false, killJob(db, s, job, sendErr)
+	}
+
+	delay := retryDelayBase << min(job.Attempts-1, 6)
+	return false, storage.ReleaseSinkJob(db, job, map[string]any{
+		"attempts":        job.Attempts,
+		"error":           sendErr.Error(),
+		"next_attempt_at": time.Now().Add(min(delay, retryDelayMax)),
+	})
+}
+
+// killJob dead-letters job, so later jobs of the sink can be delivered.
+func killJob(db *gorm.DB, s Sink, job *storage.SinkJob, err error) error {
+	logrus.Errorf("[SNK] dead-lettering job %d of %s after [%d] attempts: %s", job.ID, s.Name(), job.Attempts, err)
+
+	return storage.ReleaseSinkJob(db, job, map[string]any{
+		"attempts": job.Attempts,
+		"error":    err.Error(),
+		"dead_at":  time.Now(),
+	})
+}
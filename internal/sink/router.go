@@ -0,0 +1,373 @@
+package sink
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const (
+	routerConsumer   = "router"
+	queueSize        = 1024
+	backfillPageSize = 1000
+	retryDelayBase   = time.Second
+	retryDelayMax    = time.Minute
+)
+
+type route struct {
+	jettonMaster string // raw form
+	eventType    string
+	queues       []*queue
+}
+
+// queue delivers payloads to single sink in order.
+type queue struct {
+	sink Sink
+	ch   chan delivery
+	// payload is taken from ch but not accepted by sink yet
+	outstanding atomic.Bool
+	// events up to resume were delivered before restart
+	resume    stream.Token
+	hasResume bool
+}
+
+// delivery is a payload of events routed up to through, which becomes
+// the sink cursor once payload is accepted.
+type delivery struct {
+	payload    Payload
+	through    stream.Token
+	hasThrough bool
+}
+
+// QueueReport describes pending work of a sink.
+type QueueReport struct {
+	Sink string
+	// payloads waiting in queue
+	Queued int
+	// payload delivery was started but not completed
+	Outstanding bool
+	// jobs dead-lettered after failed deliveries, outbox mode only
+	Dead int
+}
+
+// Router consumes committed events and delivers them to sinks
+// according to routing rules. In outbox mode payloads are stored as
+// sink jobs instead and delivered by separate sink workers.
+type Router struct {
+	broker *stream.Broker
+	routes []route
+	dflt   []*queue
+	queues map[string]*queue
+	outbox bool
+
+	last    stream.Token
+	hasLast bool
+}
+
+func NewRouter(cfg *Config, broker *stream.Broker, outbox bool) *Router {
+	r := &Router{
+		broker: broker,
+		queues: make(map[string]*queue, len(cfg.Sinks)),
+		outbox: outbox,
+	}
+
+	for name, sc := range cfg.Sinks {
+		r.queues[name] = &queue{sink: newSink(name, sc), ch: make(chan delivery, queueSize)}
+	}
+
+	for _, rc := range cfg.Routes {
+		rt := route{eventType: rc.Type}
+		if rc.JettonMaster != "" {
+			// validated on config load
+			addr, _ := parseAddr(rc.JettonMaster)
+			rt.jettonMaster = rawString(addr)
+		}
+		for _, name := range rc.Sinks {
+			rt.queues = append(rt.queues, r.queues[name])
+		}
+		r.routes = append(r.routes, rt)
+	}
+	for _, name := range cfg.Default {
+		r.dflt = append(r.dflt, r.queues[name])
+	}
+
+	return r
+}
+
+// Report returns pending work of every sink, ordered by sink name.
+func (r *Router) Report() []QueueReport {
+	if r.outbox {
+		return r.outboxReport()
+	}
+
+	reports := make([]QueueReport, 0, len(r.queues))
+	for name, q := range r.queues {
+		reports = append(reports, QueueReport{
+			Sink:        name,
+			Queued:      len(q.ch),
+			Outstanding: q.outstanding.Load(),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Sink < reports[j].Sink
+	})
+
+	return reports
+}
+
+// Run routes events until ctx is done, resuming from stored cursors.
+func (r *Router) Run(ctx context.Context) {
+	if err := r.loadCursors(ctx); err != nil {
+		logrus.Errorf("[SNK] failed to load sink cursors, starting from head: %s", err)
+	}
+	if !r.outbox {
+		for _, q := range r.queues {
+			go q.run(ctx)
+		}
+	}
+
+	for {
+		if !r.consume(ctx) {
+			return
+		}
+		logrus.Warnf("[SNK] router subscription dropped, resuming from %s", r.last)
+	}
+}
+
+// consume returns false when ctx is done and true if subscription was dropped.
+func (r *Router) consume(ctx context.Context) bool {
+	var delivered uint32
+	if r.hasLast {
+		delivered = r.last.SeqNo
+	}
+	sub := r.broker.Subscribe(routerConsumer, delivered)
+	defer r.broker.Unsubscribe(sub)
+
+	if r.hasLast {
+		if err := r.backfill(ctx, sub); err != nil {
+			logrus.Errorf("[SNK] failed to backfill events: %s", err)
+			return ctx.Err() == nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case batch, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			events := make([]storage.Event, 0, len(batch.Events))
+			for _, e := range batch.Events {
+				token := stream.TokenOf(e)
+				if r.hasLast && !r.last.Less(token) {
+					continue
+				}
+				events = append(events, e)
+			}
+			if !r.route(ctx, events, batch.Corrections) {
+				return false
+			}
+			if len(events) > 0 {
+				r.last, r.hasLast = stream.TokenOf(events[len(events)-1]), true
+			}
+			sub.Delivered(batch.Head)
+		}
+	}
+}
+
+func (r *Router) backfill(ctx context.Context, sub *stream.Subscription) error {
+	for {
+		events, err := stream.EventsAfter(r.last, backfillPageSize)
+		if err != nil {
+			return err
+		}
+		if len(events) > 0 {
+			if !r.route(ctx, events, nil) {
+				return ctx.Err()
+			}
+			r.last = stream.TokenOf(events[len(events)-1])
+			sub.Delivered(r.last.SeqNo)
+		}
+		if len(events) < backfillPageSize {
+			return nil
+		}
+	}
+}
+
+// route enqueues events to matching sinks, it returns false if ctx is done.
+// Events delivered to sink before restart are skipped for it.
+func (r *Router) route(ctx context.Context, events []storage.Event, corrections []stream.Correction) bool {
+	payloads := make(map[*queue]*Payload)
+	get := func(q *queue) *Payload {
+		p, ok := payloads[q]
+		if !ok {
+			p = &Payload{}
+			payloads[q] = p
+		}
+		return p
+	}
+
+	for _, e := range events {
+		token := stream.TokenOf(e)
+		for _, q := range r.match(e) {
+			if q.hasResume && !q.resume.Less(token) {
+				continue
+			}
+			p := get(q)
+			p.Events = append(p.Events, e)
+		}
+	}
+	d := delivery{}
+	if len(events) > 0 {
+		d.through, d.hasThrough = stream.TokenOf(events[len(events)-1]), true
+	}
+	// corrections go to every sink which could get any of affected events
+	for _, c := range corrections {
+		matched := make(map[*queue]struct{})
+		for _, e := range append(c.Superseded, c.Events...) {
+			for _, q := range r.match(e) {
+				matched[q] = struct{}{}
+			}
+		}
+		for q := range matched {
+			p := get(q)
+			p.Corrections = append(p.Corrections, c)
+		}
+	}
+
+	if r.outbox {
+		return r.enqueue(ctx, payloads, d)
+	}
+	// every sink gets the batch, so cursors of sinks without matching
+	// events move as well and don't hold back resume after restart
+	for _, q := range r.queues {
+		p, ok := payloads[q]
+		if !ok && !(d.hasThrough && q.behind(d.through)) {
+			continue
+		}
+		d.payload = Payload{}
+		if ok {
+			d.payload = *p
+		}
+		select {
+		case q.ch <- d:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Router) match(e storage.Event) []*queue {
+	for _, rt := range r.routes {
+		if rt.eventType != "" && rt.eventType != e.Type {
+			continue
+		}
+		if rt.jettonMaster != "" && rt.jettonMaster != rawAddr(e.JettonMaster) {
+			continue
+		}
+		return rt.queues
+	}
+
+	return r.dflt
+}
+
+func (q *queue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-q.ch:
+			q.deliver(ctx, d)
+		}
+	}
+}
+
+// behind reports whether sink cursor is before token.
+func (q *queue) behind(t stream.Token) bool {
+	return !q.hasResume || q.resume.Less(t)
+}
+
+// deliver retries until payload is accepted by sink and moves the sink
+// cursor. Empty payloads only move the cursor. Failure to store cursor
+// only means redelivery after restart.
+func (q *queue) deliver(ctx context.Context, d delivery) {
+	q.outstanding.Store(true)
+	delay := retryDelayBase
+	for {
+		var err error
+		if len(d.payload.Events) > 0 || len(d.payload.Corrections) > 0 {
+			err = q.sink.Send(ctx, d.payload)
+		}
+		if err == nil {
+			q.outstanding.Store(false)
+			if d.hasThrough {
+				cursor := storage.SinkCursor{
+					Sink:       q.sink.Name(),
+					SeqNo:      d.through.SeqNo,
+					EventIndex: d.through.Index,
+				}
+				if err := storage.SaveSinkCursors(app.DB, []storage.SinkCursor{cursor}); err != nil {
+					logrus.Errorf("[SNK] failed to save cursor of %s: %s", q.sink.Name(), err)
+				}
+			}
+			return
+		}
+		logrus.Errorf("[SNK] failed to deliver to %s: %s", q.sink.Name(), err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, retryDelayMax)
+	}
+}
+
+// loadCursors sets resume points of sinks. Router starts from the
+// oldest one, sinks without cursor get events from there as well.
+// Without any cursor router starts from head.
+func (r *Router) loadCursors(ctx context.Context) error {
+	var cursors []storage.SinkCursor
+	if err := app.DB.WithContext(ctx).Find(&cursors).Error; err != nil {
+		return err
+	}
+
+	for _, c := range cursors {
+		q, ok := r.queues[c.Sink]
+		if !ok {
+			// sink was removed from config
+			continue
+		}
+		q.resume, q.hasResume = stream.Token{SeqNo: c.SeqNo, Index: c.EventIndex}, true
+		if !r.hasLast || q.resume.Less(r.last) {
+			r.last, r.hasLast = q.resume, true
+		}
+	}
+	if r.hasLast {
+		logrus.Infof("[SNK] resuming sinks from %s", r.last)
+	}
+
+	return nil
+}
+
+func rawAddr(s string) string {
+	if s == "" {
+		return ""
+	}
+	addr, err := parseAddr(s)
+	if err != nil {
+		return s
+	}
+	return rawString(addr)
+}
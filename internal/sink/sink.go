@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/eventpb"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/stream"
+)
+
+const (
+	TypeWebhook = "webhook"
+	TypeLog     = "log"
+
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+
+	SignatureHeader = "X-Signature"
+	webhookTimeout  = 10 * time.Second
+)
+
+// Payload is a portion of events delivered to sink.
+type Payload struct {
+	Events      []storage.Event     `json:"events,omitempty"`
+	Corrections []stream.Correction `json:"corrections,omitempty"`
+}
+
+// allEvents returns events of payload and its corrections.
+func (p *Payload) allEvents() []storage.Event {
+	events := slices.Clone(p.Events)
+	for _, c := range p.Corrections {
+		events = append(events, c.Superseded...)
+		events = append(events, c.Events...)
+	}
+
+	return events
+}
+
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, p Payload) error
+}
+
+func newSink(name string, sc SinkConfig) Sink {
+	if sc.Type == TypeWebhook {
+		return newWebhook(name, sc.URL, sc.Secret, sc.Encoding)
+	}
+
+	return logSink{name: name}
+}
+
+// webhook posts payload as JSON or protobuf, body is signed
+// with HMAC-SHA256 if secret is set.
+type webhook struct {
+	name     string
+	url      string
+	secret   string
+	encoding string
+	client   *http.Client
+}
+
+func newWebhook(name, url, secret, encoding string) *webhook {
+	return &webhook{
+		name:     name,
+		url:      url,
+		secret:   secret,
+		encoding: encoding,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (w *webhook) Name() string {
+	return w.name
+}
+
+func (w *webhook) Send(ctx context.Context, p Payload) error {
+	body, contentType, err := w.encode(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *webhook) encode(p Payload) ([]byte, string, error) {
+	if w.encoding == EncodingProtobuf {
+		return eventpb.MarshalPayload(p.Events, p.Corrections), eventpb.ContentType, nil
+	}
+
+	body, err := json.Marshal(p)
+	return body, "application/json", err
+}
+
+// logSink only logs events, useful for debugging of routes.
+type logSink struct {
+	name string
+}
+
+func (l logSink) Name() string {
+	return l.name
+}
+
+func (l logSink) Send(_ context.Context, p Payload) error {
+	for _, e := range p.Events {
+		logrus.Infof("[SNK] %s: %s %s from %s to %s", l.name, e.Type, e.Amount, e.Sender, e.Recipient)
+	}
+	for _, c := range p.Corrections {
+		logrus.Infof("[SNK] %s: correction of block %d", l.name, c.SeqNo)
+	}
+
+	return nil
+}
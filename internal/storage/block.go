@@ -1,10 +1,48 @@
 package storage
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Block struct {
 	SeqNo       uint32 `gorm:"primaryKey;autoIncrement:false;"`
 	Workchain   int32
 	Shard       int64
+	TxCount     *uint32
 	ProcessedAt time.Time
 }
+
+// Gap is a range of master blocks missing between stored ones.
+type Gap struct {
+	From uint32 `json:"from"`
+	To   uint32 `json:"to"`
+}
+
+// gapWindow is the number of last master blocks searched for gaps, about
+// a week of blocks, so the search doesn't read the whole table.
+const gapWindow = 100_000
+
+// FindGaps returns up to limit ranges of missing master blocks among
+// the last gapWindow blocks. Blocks before the first stored one aren't
+// missing, scanner started after them.
+func FindGaps(db *gorm.DB, limit int) ([]Gap, error) {
+	var gaps []Gap
+	err := db.Raw(`
+		WITH bounds AS (
+			SELECT min(seq_no) AS lo, max(seq_no) AS hi FROM blocks
+		), missing AS (
+			SELECT s, s - row_number() OVER (ORDER BY s) AS grp
+			FROM bounds, generate_series(greatest(lo, hi - ?), hi) s
+			WHERE NOT EXISTS (SELECT 1 FROM blocks WHERE seq_no = s)
+		)
+		SELECT min(s) AS "from", max(s) AS "to"
+		FROM missing
+		GROUP BY grp
+		ORDER BY 1
+		LIMIT ?`, gapWindow, limit).
+		Scan(&gaps).Error
+
+	return gaps, err
+}
@@ -0,0 +1,15 @@
+package storage
+
+import "time"
+
+// UnresolvedCell is an exotic cell in message body which couldn't be
+// resolved, transaction should be processed again when it's possible.
+type UnresolvedCell struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	SeqNo     uint32    `gorm:"index" json:"seqno"`
+	TxHash    string    `json:"tx_hash"`
+	Handler   string    `json:"handler,omitempty"`
+	CellType  string    `json:"cell_type"`
+	CellHash  string    `json:"cell_hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "time"
+
+// PendingCorrection is a correction stored by standalone tools, e.g.
+// reparse or account indexer, which have no subscribers of their own.
+// Running scanner publishes it to its subscribers and deletes it.
+type PendingCorrection struct {
+	ID            uint64   `gorm:"primaryKey"`
+	SeqNo         uint32   `gorm:"index"`
+	EventIDs      []uint64 `gorm:"serializer:json"`
+	SupersededIDs []uint64 `gorm:"serializer:json"`
+	CreatedAt     time.Time
+}
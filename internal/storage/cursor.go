@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SinkCursor is a position in the event log up to which events were
+// delivered to sink, or stored as its jobs in outbox mode. Router
+// resumes from cursors after restart.
+type SinkCursor struct {
+	Sink       string `gorm:"primaryKey"`
+	SeqNo      uint32
+	EventIndex uint32
+	UpdatedAt  time.Time
+}
+
+func SaveSinkCursors(db *gorm.DB, cursors []SinkCursor) error {
+	if len(cursors) == 0 {
+		return nil
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sink"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seq_no", "event_index", "updated_at"}),
+	}).Create(&cursors).Error
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Deletion is an audit record of data removal request. Address is
+// kept only as a hash, so the record itself holds no personal data
+// but a repeated request for the same address can be matched.
+type Deletion struct {
+	ID            uint64    `gorm:"primaryKey" json:"id"`
+	AddressHash   string    `gorm:"index" json:"address_hash"`
+	Events        int64     `json:"events"`
+	ShadowEvents  int64     `json:"shadow_events"`
+	Summaries     int64     `json:"summaries"`
+	Watches       int64     `json:"watches"`
+	WalletCodes   int64     `json:"wallet_codes"`    // jetton wallet codes which had it as sample wallet
+	SinkJobEvents int64     `json:"sink_job_events"` // events removed from undelivered sink jobs
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// sinkPayload mirrors JSON of sink.Payload, storage can't import sink.
+type sinkPayload struct {
+	Events      []Event          `json:"events,omitempty"`
+	Corrections []sinkCorrection `json:"corrections,omitempty"`
+}
+
+// sinkCorrection mirrors JSON of stream.Correction.
+type sinkCorrection struct {
+	SeqNo      uint32  `json:"seqno"`
+	Superseded []Event `json:"superseded"`
+	Events     []Event `json:"events"`
+}
+
+// eventAddressQuery matches events and shadow events mentioning address
+// in any of its forms.
+const eventAddressQuery = "sender IN @forms OR recipient IN @forms OR nft_item IN @forms OR jetton_master IN @forms"
+
+// PurgeAddress removes all stored data mentioning the address, including
+// superseded revisions of events and events of undelivered sink jobs,
+// suppresses its indexing and records the deletion in one db transaction.
+func PurgeAddress(db *gorm.DB, addr *address.Address) (*Deletion, error) {
+	raw := fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())
+	forms := addressForms(addr)
+	d := Deletion{AddressHash: AddressHash(addr)}
+	byForms := map[string]any{"forms": forms}
+
+	err := db.Transaction(func(tx *gorm.DB) (err error) {
+		// suppressed first, so writer drops events committed after purge
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&SuppressedAddress{AddressHash: d.AddressHash}).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().Where(eventAddressQuery, byForms).Delete(&Event{})
+		if res.Error != nil {
+			return res.Error
+		}
+		d.Events = res.RowsAffected
+
+		res = tx.Where(eventAddressQuery, byForms).Delete(&ShadowEvent{})
+		if res.Error != nil {
+			return res.Error
+		}
+		d.ShadowEvents = res.RowsAffected
+
+		res = tx.Where("address IN ?", forms).Delete(&EventSummary{})
+		if res.Error != nil {
+			return res.Error
+		}
+		d.Summaries = res.RowsAffected
+
+		res = tx.Where("address = ?", raw).Delete(&AccountWatch{})
+		if res.Error != nil {
+			return res.Error
+		}
+		d.Watches = res.RowsAffected
+
+		// the code itself isn't personal data
+		res = tx.Model(&JettonWalletCode{}).Where("wallet IN ?", forms).Update("wallet", "")
+		if res.Error != nil {
+			return res.Error
+		}
+		d.WalletCodes = res.RowsAffected
+
+		d.SinkJobEvents, err = purgeSinkJobs(tx, forms, d.AddressHash)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&d).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// purgeSinkJobs removes events of address from payloads of sink jobs,
+// jobs left without events are deleted. Only jobs which mention address
+// hash are decrypted, and jobs stored before hashes were recorded.
+// Payloads which can't be decrypted hold nothing readable and are left
+// to sink workers.
+func purgeSinkJobs(tx *gorm.DB, forms []string, hash string) (int64, error) {
+	match := make(map[string]struct{}, len(forms))
+	for _, f := range forms {
+		match[f] = struct{}{}
+	}
+	strip := func(events []Event) ([]Event, int64) {
+		kept := events[:0]
+		for _, e := range events {
+			_, sender := match[e.Sender]
+			_, recipient := match[e.Recipient]
+			_, item := match[e.NftItem]
+			_, master := match[e.JettonMaster]
+			if !sender && !recipient && !item && !master {
+				kept = append(kept, e)
+			}
+		}
+		return kept, int64(len(events) - len(kept))
+	}
+
+	var ids []uint64
+	hashes, err := json.Marshal([]string{hash})
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&SinkJob{}).
+		Where("address_hashes IS NULL OR address_hashes @> ?::jsonb", string(hashes)).
+		Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, id := range ids {
+		job := &SinkJob{ID: id}
+		data, err := SinkJobPayload(tx, job)
+		if errors.Is(err, ErrBrokenPayload) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		var p sinkPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			continue
+		}
+
+		var n, jobRemoved int64
+		p.Events, jobRemoved = strip(p.Events)
+		left := slices.Clone(p.Events)
+		for i := range p.Corrections {
+			c := &p.Corrections[i]
+			c.Superseded, n = strip(c.Superseded)
+			jobRemoved += n
+			c.Events, n = strip(c.Events)
+			jobRemoved += n
+			left = append(left, c.Superseded...)
+			left = append(left, c.Events...)
+		}
+		if jobRemoved == 0 {
+			continue
+		}
+		removed += jobRemoved
+
+		if len(left) == 0 {
+			if err := tx.Delete(job).Error; err != nil {
+				return 0, err
+			}
+			continue
+		}
+		data, err = json.Marshal(p)
+		if err != nil {
+			return 0, err
+		}
+		update := SinkJob{Payload: EncryptedString(data), AddressHashes: EventAddressHashes(left)}
+		if err := tx.Model(job).Updates(update).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return removed, nil
+}
+
+// addressForms returns all textual forms address can be stored in:
+// user-friendly with any flags and raw.
+func addressForms(addr *address.Address) []string {
+	forms := []string{fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())}
+	for _, bounce := range []bool{true, false} {
+		for _, testnet := range []bool{true, false} {
+			forms = append(forms, addr.Copy().Bounce(bounce).Testnet(testnet).String())
+		}
+	}
+
+	return forms
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/fieldcrypt"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// EncryptedString is a text column encrypted with the key set by
+// fieldcrypt.SetKey, fields are tagged with serializer:encrypted.
+// Ciphertext is bound to table column. Values are stored escaped
+// without key, and rows stored before the key was set are still read
+// as plain text.
+type EncryptedString string
+
+// GormDataType keeps column text, encrypted values are longer than plain.
+func (EncryptedString) GormDataType() string {
+	return "text"
+}
+
+// encryptedSerializer encrypts EncryptedString fields in scope of their
+// table column.
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, v any) (any, error) {
+	s, ok := v.(EncryptedString)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T of encrypted column", v)
+	}
+
+	return fieldcrypt.Encrypt(string(s), columnScope(field.Schema.Table, field.DBName))
+}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, src any) error {
+	var v string
+	switch t := src.(type) {
+	case nil:
+	case string:
+		v = t
+	case []byte:
+		v = string(t)
+	default:
+		return fmt.Errorf("unsupported type %T of encrypted column", src)
+	}
+
+	plain, err := decryptColumn(v, field.Schema.Table, field.DBName)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plain)
+
+	return nil
+}
+
+func columnScope(table, column string) string {
+	return table + "." + column
+}
+
+// decryptColumn decrypts value of table column. Values of the legacy
+// format which can't be decrypted are plain ones from chain data, they
+// were stored before plain values got escaped.
+func decryptColumn(v, table, column string) (string, error) {
+	plain, err := fieldcrypt.Decrypt(v, columnScope(table, column))
+	if err != nil {
+		if fieldcrypt.IsLegacy(v) {
+			return v, nil
+		}
+		return "", fmt.Errorf("failed to decrypt %s.%s: %w", table, column, err)
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventTypeJettonTransfer = "jetton_transfer"
+	EventTypeTonTransfer    = "ton_transfer"
+	EventTypeNftTransfer    = "nft_transfer"
+	EventTypeSwap           = "swap"
+	EventTypeConfigChanged  = "config_changed"
+	EventTypeMintlessClaim  = "mintless_claim"
+	EventTypeSuspicious     = "suspicious" // emulated outcome differs from observed
+	// TEP-85 soulbound tokens
+	EventTypeSBTProveOwnership = "sbt_prove_ownership"
+	EventTypeSBTRevoke         = "sbt_revoke"
+	EventTypeSBTDestroy        = "sbt_destroy"
+)
+
+var EventTypes = map[string]struct{}{
+	EventTypeJettonTransfer: {},
+	EventTypeTonTransfer:    {},
+	EventTypeNftTransfer:    {},
+	EventTypeSwap:           {},
+	EventTypeConfigChanged:  {},
+	EventTypeMintlessClaim:  {},
+	EventTypeSuspicious:     {},
+
+	EventTypeSBTProveOwnership: {},
+	EventTypeSBTRevoke:         {},
+	EventTypeSBTDestroy:        {},
+}
+
+// DecoderEventPrefix starts types of events of wasm decoders. Decoders
+// are untrusted, so their types are namespaced by decoder name, e.g.
+// wasm:dex:swap, and they can't pass for built-in events.
+const DecoderEventPrefix = "wasm:"
+
+// IsEventType reports whether t is a built-in type or a type of decoder.
+func IsEventType(t string) bool {
+	if _, ok := EventTypes[t]; ok {
+		return true
+	}
+	name, ok := strings.CutPrefix(t, DecoderEventPrefix)
+	if !ok {
+		return false
+	}
+	decoder, typ, ok := strings.Cut(name, ":")
+
+	return ok && decoder != "" && typ != "" && !strings.Contains(typ, ":")
+}
+
+type Event struct {
+	ID               uint64          `gorm:"primaryKey;index:idx_events_type_id,priority:2;index:idx_events_master_type_id,priority:3;index:idx_events_opcode_id,priority:2" json:"id"`
+	Type             string          `gorm:"index:idx_events_type_id,priority:1;index:idx_events_master_type_id,priority:2" json:"type"`
+	SeqNo            uint32          `gorm:"index:idx_events_seqno_index,priority:1" json:"seqno"`
+	EventIndex       uint32          `gorm:"index:idx_events_seqno_index,priority:2" json:"event_index"`
+	LT               uint64          `json:"lt"`
+	UTime            uint32          `json:"utime"` // transaction time
+	TxHash           string          `json:"tx_hash"`
+	Opcode           uint32          `gorm:"index:idx_events_opcode_id,priority:1" json:"opcode"`
+	JettonMaster     string          `gorm:"index:idx_events_master_type_id,priority:1;index:idx_events_master_amount,priority:1" json:"jetton_master,omitempty"`
+	JettonWalletCode string          `json:"jetton_wallet_code,omitempty"`
+	JettonWalletType string          `json:"jetton_wallet_type,omitempty"`
+	Sender           string          `json:"sender"`
+	Recipient        string          `json:"recipient"`
+	NftItem          string          `json:"nft_item,omitempty"`
+	Amount           string          `gorm:"type:numeric(78,0);index:idx_events_master_amount,priority:2" json:"amount"`
+	Comment          EncryptedString `gorm:"serializer:encrypted" json:"comment,omitempty"`
+	Payload          string          `json:"payload,omitempty"`
+	CustomPayload    string          `json:"custom_payload,omitempty"`
+	ConfigParam      *int32          `json:"config_param,omitempty"`
+	ConfigValue      string          `json:"config_value,omitempty"`
+	Success          bool            `json:"success"`
+	Revision         uint32          `json:"revision"`
+	CreatedAt        time.Time       `json:"created_at"`
+	DeletedAt        gorm.DeletedAt  `gorm:"index" json:"deleted_at"`
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FailedBlock is a dead-letter entry of master block which transactions
+// failed to process, usually because of a critical handler error. The
+// block stays unindexed until it's rerun successfully.
+type FailedBlock struct {
+	SeqNo    uint32 `gorm:"primaryKey" json:"seqno"`
+	Handler  string `json:"handler,omitempty"`
+	Error    string `json:"error"`
+	Attempts uint32 `json:"attempts"`
+	// handler set of the rerun which resolved the block
+	RerunHandlers string     `json:"rerun_handlers,omitempty"`
+	ResolvedAt    *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// AddFailedBlock records failure of block, attempts of known block are
+// incremented and its resolution is reset.
+func AddFailedBlock(db *gorm.DB, seqno uint32, handler string, err error) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "seq_no"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"handler":        handler,
+			"error":          err.Error(),
+			"attempts":       gorm.Expr("failed_blocks.attempts + 1"),
+			"rerun_handlers": "",
+			"resolved_at":    nil,
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&FailedBlock{
+		SeqNo:    seqno,
+		Handler:  handler,
+		Error:    err.Error(),
+		Attempts: 1,
+	}).Error
+}
@@ -0,0 +1,12 @@
+package storage
+
+import "time"
+
+// IndexedTx is a transaction indexed by account history walk. Its events
+// can be compacted or suppressed, so the walk can't tell it was indexed by
+// events alone and would store them again.
+type IndexedTx struct {
+	TxHash    string    `gorm:"primaryKey" json:"tx_hash"`
+	SeqNo     uint32    `gorm:"index" json:"seqno"`
+	CreatedAt time.Time `json:"created_at"`
+}
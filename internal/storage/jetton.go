@@ -0,0 +1,21 @@
+package storage
+
+import "time"
+
+const (
+	JettonWalletStandard = "standard"
+	// wallets with status (lock) control by admin, like USDT
+	JettonWalletGoverned = "governed"
+	// wallets with claimable balances proven by merkle proof
+	JettonWalletMintless = "mintless"
+)
+
+// JettonWalletCode is a jetton wallet implementation seen in notifications.
+type JettonWalletCode struct {
+	CodeHash   string `gorm:"primaryKey" json:"code_hash"`
+	Type       string `json:"type"`
+	FirstSeqNo uint32 `json:"first_seqno"`
+	// one of wallets with this code
+	Wallet    string    `json:"wallet"`
+	CreatedAt time.Time `json:"created_at"`
+}
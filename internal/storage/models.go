@@ -0,0 +1,22 @@
+package storage
+
+// Models returns all db models, in migration order.
+func Models() []any {
+	return []any{
+		&Block{},
+		&Event{},
+		&Stat{},
+		&UnresolvedCell{},
+		&JettonWalletCode{},
+		&ShadowEvent{},
+		&EventSummary{},
+		&AccountWatch{},
+		&FailedBlock{},
+		&Deletion{},
+		&SinkJob{},
+		&PendingCorrection{},
+		&SinkCursor{},
+		&IndexedTx{},
+		&SuppressedAddress{},
+	}
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrBrokenPayload is returned for sink job payload which can't be
+	// decrypted, such job can never be delivered.
+	ErrBrokenPayload = errors.New("broken sink job payload")
+	// ErrLeaseLost is returned when lease of sink job expired and the job
+	// was claimed again or changed meanwhile.
+	ErrLeaseLost = errors.New("sink job lease lost")
+)
+
+// SinkJob is a payload waiting for delivery to sink by sink workers.
+// Payload is JSON of sink.Payload, encrypted like other sensitive
+// columns because it includes comments. Dead jobs failed too many
+// times, they are kept for inspection but never delivered.
+type SinkJob struct {
+	ID            uint64          `gorm:"primaryKey" json:"id"`
+	Sink          string          `gorm:"index:idx_sink_jobs_sink_id,priority:1" json:"sink"`
+	Payload       EncryptedString `gorm:"serializer:encrypted" json:"-"`
+	Attempts      uint32          `json:"attempts"`
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LeasedUntil   *time.Time      `json:"leased_until,omitempty"` // job is being delivered
+	// hashes of addresses mentioned by payload, so jobs of purged
+	// address are found without decrypting all payloads
+	AddressHashes []string   `gorm:"serializer:json;type:jsonb;index:,type:gin" json:"-"`
+	DeadAt        *time.Time `json:"dead_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// SinkQueue is a number of jobs waiting for delivery to sink
+// and dead ones.
+type SinkQueue struct {
+	Sink string
+	Jobs int64
+	Dead int64
+}
+
+// ClaimSinkJob leases the oldest live job of sink if it's due and
+// returns it without payload, see SinkJobPayload. The lease is taken in
+// a short transaction and marks job in flight, so job can be delivered
+// without holding db transaction. Jobs of a sink are delivered one by
+// one in order, so nil is returned if the oldest job is being claimed
+// or delivered by another worker. Lease of crashed worker expires.
+func ClaimSinkJob(db *gorm.DB, sink string, lease time.Duration) (*SinkJob, error) {
+	var claimed *SinkJob
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var oldest SinkJob
+		err := tx.Select("id").
+			Where("sink = ? AND dead_at IS NULL", sink).
+			Order("id").
+			Take(&oldest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// the oldest job is locked by id, so concurrent claims skip the
+		// sink instead of taking later jobs out of order
+		var job SinkJob
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Omit("payload").
+			Where("id = ? AND dead_at IS NULL", oldest.ID).
+			Take(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if job.LeasedUntil != nil && job.LeasedUntil.After(now) {
+			return nil
+		}
+		// the oldest job blocks the later ones until it's delivered
+		if job.NextAttemptAt.After(now) {
+			return nil
+		}
+
+		// db keeps microseconds, the lease is compared on release
+		until := now.Add(lease).Truncate(time.Microsecond)
+		if err := tx.Model(&job).Update("leased_until", until).Error; err != nil {
+			return err
+		}
+		job.LeasedUntil = &until
+		claimed = &job
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// CompleteSinkJob deletes delivered job leased by ClaimSinkJob.
+func CompleteSinkJob(db *gorm.DB, job *SinkJob) error {
+	res := leased(db, job).Delete(&SinkJob{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// ReleaseSinkJob updates job leased by ClaimSinkJob and ends the lease.
+func ReleaseSinkJob(db *gorm.DB, job *SinkJob, updates map[string]any) error {
+	updates["leased_until"] = nil
+	res := leased(db, job).Model(&SinkJob{}).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+func leased(db *gorm.DB, job *SinkJob) *gorm.DB {
+	return db.Where("id = ? AND leased_until = ?", job.ID, job.LeasedUntil)
+}
+
+// SinkJobPayload loads payload of claimed job. Payload which can't be
+// decrypted is reported as ErrBrokenPayload.
+func SinkJobPayload(tx *gorm.DB, job *SinkJob) ([]byte, error) {
+	var raw string
+	err := tx.Model(&SinkJob{}).Select("payload").Where("id = ?", job.ID).Row().Scan(&raw)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decryptColumn(raw, "sink_jobs", "payload")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBrokenPayload, err)
+	}
+
+	return []byte(payload), nil
+}
+
+// CountSinkJobs returns number of pending and dead jobs per sink.
+func CountSinkJobs(db *gorm.DB) ([]SinkQueue, error) {
+	var queues []SinkQueue
+	err := db.Model(&SinkJob{}).
+		Select("sink, count(*) FILTER (WHERE dead_at IS NULL) AS jobs, count(*) FILTER (WHERE dead_at IS NOT NULL) AS dead").
+		Group("sink").
+		Order("sink").
+		Scan(&queues).Error
+
+	return queues, err
+}
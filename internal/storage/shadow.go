@@ -0,0 +1,26 @@
+package storage
+
+import "time"
+
+// ShadowEvent is an output or error of handler running in shadow mode.
+// Shadow events are not published, they are kept for comparison with
+// events of live handlers.
+type ShadowEvent struct {
+	ID            uint64          `gorm:"primaryKey" json:"id"`
+	Handler       string          `gorm:"index:idx_shadow_events_handler_seqno,priority:1" json:"handler"`
+	SeqNo         uint32          `gorm:"index:idx_shadow_events_handler_seqno,priority:2" json:"seqno"`
+	LT            uint64          `json:"lt"`
+	TxHash        string          `json:"tx_hash"`
+	Type          string          `json:"type,omitempty"`
+	Opcode        uint32          `json:"opcode"`
+	JettonMaster  string          `json:"jetton_master,omitempty"`
+	Sender        string          `json:"sender,omitempty"`
+	Recipient     string          `json:"recipient,omitempty"`
+	NftItem       string          `json:"nft_item,omitempty"`
+	Amount        string          `json:"amount,omitempty"`
+	Comment       EncryptedString `gorm:"serializer:encrypted" json:"comment,omitempty"`
+	Payload       string          `json:"payload,omitempty"`
+	CustomPayload string          `json:"custom_payload,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stat is scanner throughput during one minute.
+type Stat struct {
+	Minute        time.Time `gorm:"primaryKey" json:"minute"`
+	Blocks        uint64    `json:"blocks"`
+	Txs           uint64    `json:"txs"`
+	Events        uint64    `json:"events"`
+	LiteserverReq uint64    `json:"liteserver_requests"`
+	Suppressed    uint64    `json:"suppressed"`
+}
+
+// AddStat adds counters to the stored minute, so several flushes
+// during the same minute are summed up.
+func AddStat(db *gorm.DB, s Stat) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "minute"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"blocks":         gorm.Expr("stats.blocks + excluded.blocks"),
+			"txs":            gorm.Expr("stats.txs + excluded.txs"),
+			"events":         gorm.Expr("stats.events + excluded.events"),
+			"liteserver_req": gorm.Expr("stats.liteserver_req + excluded.liteserver_req"),
+			"suppressed":     gorm.Expr("stats.suppressed + excluded.suppressed"),
+		}),
+	}).Create(&s).Error
+}
+
+// DeleteStatsBefore removes stats older than given time.
+func DeleteStatsBefore(db *gorm.DB, t time.Time) error {
+	return db.Where("minute < ?", t).Delete(&Stat{}).Error
+}
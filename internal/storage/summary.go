@@ -0,0 +1,19 @@
+package storage
+
+import "time"
+
+// EventSummary aggregates events of compacted address in one master block,
+// they are stored instead of individual events. Amount is a sum of
+// event amounts.
+type EventSummary struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	SeqNo     uint32    `gorm:"index:idx_event_summaries_label_seqno,priority:2;index" json:"seqno"`
+	Label     string    `gorm:"index:idx_event_summaries_label_seqno,priority:1" json:"label"`
+	Address   string    `json:"address"`
+	Type      string    `json:"type"`
+	Count     uint32    `json:"count"`
+	Amount    string    `gorm:"type:numeric(78,0)" json:"amount"`
+	FirstLT   uint64    `json:"first_lt"`
+	LastLT    uint64    `json:"last_lt"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"gorm.io/gorm"
+)
+
+// ErrSuppressed is returned for address which was purged, it must not
+// be indexed again.
+var ErrSuppressed = errors.New("address was purged")
+
+// SuppressedAddress is an address purged by request. Writer drops its
+// new events and account subscriptions reject it. Like Deletion it
+// holds only hash of the address.
+type SuppressedAddress struct {
+	AddressHash string `gorm:"primaryKey"`
+	CreatedAt   time.Time
+}
+
+// AddressHash returns hex SHA-256 of raw form of address.
+func AddressHash(addr *address.Address) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsSuppressed reports whether address was purged.
+func IsSuppressed(db *gorm.DB, addr *address.Address) (bool, error) {
+	var n int64
+	err := db.Model(&SuppressedAddress{}).Where("address_hash = ?", AddressHash(addr)).Count(&n).Error
+
+	return n > 0, err
+}
+
+// SuppressedAddresses returns hashes of purged addresses.
+func SuppressedAddresses(db *gorm.DB) (map[string]struct{}, error) {
+	var hashes []string
+	if err := db.Model(&SuppressedAddress{}).Pluck("address_hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	suppressed := make(map[string]struct{}, len(hashes))
+	for _, h := range hashes {
+		suppressed[h] = struct{}{}
+	}
+
+	return suppressed, nil
+}
+
+// FilterSuppressed returns events which mention no suppressed address
+// and number of dropped ones.
+func FilterSuppressed(events []Event, suppressed map[string]struct{}) ([]Event, int) {
+	if len(suppressed) == 0 {
+		return events, 0
+	}
+
+	kept := events[:0]
+	for _, e := range events {
+		drop := false
+		for _, h := range eventAddressHashes(&e) {
+			if _, ok := suppressed[h]; ok {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, e)
+		}
+	}
+
+	return kept, len(events) - len(kept)
+}
+
+// EventAddressHashes returns distinct hashes of addresses mentioned by
+// events.
+func EventAddressHashes(events []Event) []string {
+	seen := make(map[string]struct{})
+	var hashes []string
+	for i := range events {
+		for _, h := range eventAddressHashes(&events[i]) {
+			if _, ok := seen[h]; !ok {
+				seen[h] = struct{}{}
+				hashes = append(hashes, h)
+			}
+		}
+	}
+
+	return hashes
+}
+
+func eventAddressHashes(e *Event) []string {
+	var hashes []string
+	for _, s := range []string{e.Sender, e.Recipient, e.NftItem, e.JettonMaster} {
+		if h := storedAddressHash(s); h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+
+	return hashes
+}
+
+// storedAddressHash returns hash of address stored in user-friendly or
+// raw form, empty for empty or invalid one.
+func storedAddressHash(s string) string {
+	if s == "" {
+		return ""
+	}
+	addr, err := address.ParseAddr(s)
+	if err != nil {
+		if addr, err = address.ParseRawAddr(s); err != nil {
+			return ""
+		}
+	}
+
+	return AddressHash(addr)
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "time"
+
+const (
+	WatchPending = "pending"
+	WatchRunning = "running"
+	WatchDone    = "done"
+	WatchFailed  = "failed"
+)
+
+// AccountWatch is an account subscribed through the api. History of the
+// account since FromTime is backfilled once, new transactions are
+// indexed by the scanner anyway.
+type AccountWatch struct {
+	Address   string    `gorm:"primaryKey" json:"address"`
+	FromTime  time.Time `json:"from_time"`
+	Status    string    `gorm:"index" json:"status"`
+	Txs       uint32    `json:"txs"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
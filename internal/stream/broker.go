@@ -0,0 +1,186 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+const (
+	subscriptionBuffer = 256
+	lagCheckInterval   = 10 * time.Second
+)
+
+// Batch is a set of committed events, Head is the last committed master block.
+type Batch struct {
+	Head        uint32
+	Events      []storage.Event
+	Corrections []Correction
+}
+
+// Correction replaces events of already delivered block after its reparse.
+// Events of blocks filled after gaps are delivered as corrections too,
+// they are behind tokens of live events. Corrections are not stored, so
+// subscribers which are disconnected or backfilling miss them and have
+// to read stored events again.
+type Correction struct {
+	SeqNo      uint32          `json:"seqno"`
+	Superseded []storage.Event `json:"superseded"`
+	Events     []storage.Event `json:"events"`
+}
+
+// Broker fans out committed events to live subscribers.
+type Broker struct {
+	head atomic.Uint32
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription receives batches in commit order.
+// C is closed when subscriber can't keep up, it should
+// reconnect with its last token then.
+type Subscription struct {
+	Consumer    string
+	C           chan Batch
+	connectedAt time.Time
+	delivered   atomic.Uint32
+	lagging     bool
+}
+
+// ConsumerLag describes how far behind the head consumer is.
+type ConsumerLag struct {
+	Consumer    string    `json:"consumer"`
+	Delivered   uint32    `json:"delivered_seqno"`
+	Head        uint32    `json:"head_seqno"`
+	Lag         uint32    `json:"lag"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers consumer which has already got events of master
+// blocks up to delivered, 0 means it starts from the current head. Lag
+// is counted from there until consumer reports delivery.
+func (b *Broker) Subscribe(consumer string, delivered uint32) *Subscription {
+	sub := &Subscription{
+		Consumer:    consumer,
+		C:           make(chan Batch, subscriptionBuffer),
+		connectedAt: time.Now(),
+	}
+	if delivered == 0 {
+		delivered = b.head.Load()
+	}
+	sub.delivered.Store(delivered)
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.C)
+	}
+}
+
+// Publish never blocks, slow subscribers are dropped.
+func (b *Broker) Publish(batch Batch) {
+	if batch.Head > b.head.Load() {
+		b.head.Store(batch.Head)
+	}
+	batch.Head = b.head.Load()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.C <- batch:
+		default:
+			logrus.Warnf("[STR] consumer %q dropped, it can't keep up", sub.Consumer)
+			delete(b.subs, sub)
+			close(sub.C)
+		}
+	}
+}
+
+// Delivered records the last master block fully delivered to subscriber.
+func (s *Subscription) Delivered(seqno uint32) {
+	s.delivered.Store(seqno)
+}
+
+func (b *Broker) Lags() []ConsumerLag {
+	head := b.head.Load()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lags := make([]ConsumerLag, 0, len(b.subs))
+	for sub := range b.subs {
+		lags = append(lags, sub.lag(head))
+	}
+
+	return lags
+}
+
+// MonitorLag periodically warns about consumers lagging behind
+// the head for more than threshold blocks.
+func (b *Broker) MonitorLag(ctx context.Context, threshold uint32) {
+	ticker := time.NewTicker(lagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head := b.head.Load()
+
+		b.mu.Lock()
+		for sub := range b.subs {
+			l := sub.lag(head)
+			switch {
+			case l.Lag > threshold && !sub.lagging:
+				sub.lagging = true
+				logrus.Warnf("[STR] consumer %q is [%d] blocks behind head [%d]",
+					sub.Consumer, l.Lag, head)
+			case l.Lag <= threshold && sub.lagging:
+				sub.lagging = false
+				logrus.Infof("[STR] consumer %q caught up with head [%d]", sub.Consumer, head)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (s *Subscription) lag(head uint32) ConsumerLag {
+	l := ConsumerLag{
+		Consumer:    s.Consumer,
+		Delivered:   s.delivered.Load(),
+		Head:        head,
+		ConnectedAt: s.connectedAt,
+	}
+	if l.Head > l.Delivered {
+		l.Lag = l.Head - l.Delivered
+	}
+
+	return l
+}
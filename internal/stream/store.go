@@ -0,0 +1,18 @@
+package stream
+
+import (
+	"github.com/qynonyq/ton_dev_go_hw3/internal/app"
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// EventsAfter returns up to limit stored events following token.
+func EventsAfter(after Token, limit int) ([]storage.Event, error) {
+	var events []storage.Event
+	err := app.DB.
+		Where("(seq_no, event_index) > (?, ?)", after.SeqNo, after.Index).
+		Order("seq_no, event_index").
+		Limit(limit).
+		Find(&events).Error
+
+	return events, err
+}
@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+)
+
+// Token is a position in the event log: master block seqno
+// and index of event inside the block.
+type Token struct {
+	SeqNo uint32
+	Index uint32
+}
+
+func TokenOf(e storage.Event) Token {
+	return Token{SeqNo: e.SeqNo, Index: e.EventIndex}
+}
+
+func ParseToken(s string) (Token, error) {
+	seqno, index, ok := strings.Cut(s, ":")
+	if !ok {
+		return Token{}, fmt.Errorf("invalid token %q", s)
+	}
+
+	sn, err := strconv.ParseUint(seqno, 10, 32)
+	if err != nil {
+		return Token{}, fmt.Errorf("invalid token seqno: %w", err)
+	}
+	idx, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return Token{}, fmt.Errorf("invalid token index: %w", err)
+	}
+
+	return Token{SeqNo: uint32(sn), Index: uint32(idx)}, nil
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%d:%d", t.SeqNo, t.Index)
+}
+
+func (t Token) Less(o Token) bool {
+	if t.SeqNo != o.SeqNo {
+		return t.SeqNo < o.SeqNo
+	}
+	return t.Index < o.Index
+}
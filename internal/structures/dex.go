@@ -0,0 +1,54 @@
+package structures
+
+import (
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// DEX swap requests: STON.fi v1 and DeDust swaps of jettons are forward
+// payloads of jetton transfers to router or vault, DeDust swaps of TON
+// are messages to native vault.
+const (
+	OpStonfiSwap       = 0x25938561
+	OpDedustNativeSwap = 0xea06185d
+	OpDedustJettonSwap = 0xe3a0d482
+)
+
+type (
+	StonfiSwap struct {
+		_           tlb.Magic        `tlb:"#25938561"`
+		TokenWallet *address.Address `tlb:"addr"` // router's wallet of asked jetton
+		MinOut      tlb.Coins        `tlb:"."`
+		ToAddress   *address.Address `tlb:"addr"`
+	}
+
+	DedustSwapStep struct {
+		PoolAddr *address.Address `tlb:"addr"`
+		GivenOut bool             `tlb:"bool"`
+		Limit    tlb.Coins        `tlb:"."`
+		Next     *cell.Cell       `tlb:"maybe ^"`
+	}
+
+	DedustSwapParams struct {
+		Deadline       uint32           `tlb:"## 32"`
+		Recipient      *address.Address `tlb:"addr"` // sender if none
+		Referral       *address.Address `tlb:"addr"`
+		FulfillPayload *cell.Cell       `tlb:"maybe ^"`
+		RejectPayload  *cell.Cell       `tlb:"maybe ^"`
+	}
+
+	DedustNativeSwap struct {
+		_       tlb.Magic        `tlb:"#ea06185d"`
+		QueryID uint64           `tlb:"## 64"`
+		Amount  tlb.Coins        `tlb:"."`
+		Step    DedustSwapStep   `tlb:"."`
+		Params  DedustSwapParams `tlb:"^"`
+	}
+
+	DedustJettonSwap struct {
+		_      tlb.Magic        `tlb:"#e3a0d482"`
+		Step   DedustSwapStep   `tlb:"."`
+		Params DedustSwapParams `tlb:"^"`
+	}
+)
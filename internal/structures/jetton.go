@@ -6,6 +6,13 @@ import (
 	"github.com/xssnick/tonutils-go/tvm/cell"
 )
 
+const (
+	OpJettonNotify       = 0x7362d09c
+	OpJettonTransfer     = 0x0f8a7ea5
+	OpJettonBurn         = 0x595f07bc
+	OpMerkleAirdropClaim = 0x0df602d6 // custom payload of mintless transfer
+)
+
 type (
 	JettonNotify struct {
 		_          tlb.Magic        `tlb:"#7362d09c"`
@@ -14,4 +21,30 @@ type (
 		Sender     *address.Address `tlb:"addr"`
 		FwdPayload *cell.Cell       `tlb:"either . ^"`
 	}
+
+	JettonTransfer struct {
+		_                   tlb.Magic        `tlb:"#0f8a7ea5"`
+		QueryID             uint64           `tlb:"## 64"`
+		Amount              tlb.Coins        `tlb:"."`
+		Destination         *address.Address `tlb:"addr"`
+		ResponseDestination *address.Address `tlb:"addr"`
+		CustomPayload       *cell.Cell       `tlb:"maybe ^"`
+		FwdTonAmount        tlb.Coins        `tlb:"."`
+		FwdPayload          *cell.Cell       `tlb:"either . ^"`
+	}
+
+	JettonBurn struct {
+		_                   tlb.Magic        `tlb:"#595f07bc"`
+		QueryID             uint64           `tlb:"## 64"`
+		Amount              tlb.Coins        `tlb:"."`
+		ResponseDestination *address.Address `tlb:"addr"`
+		CustomPayload       *cell.Cell       `tlb:"maybe ^"`
+	}
+
+	// AirdropItem is a value of mintless jetton airdrop dict keyed by owner address.
+	AirdropItem struct {
+		Amount    tlb.Coins `tlb:"."`
+		StartFrom uint64    `tlb:"## 48"`
+		ExpireAt  uint64    `tlb:"## 48"`
+	}
 )
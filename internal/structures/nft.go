@@ -0,0 +1,24 @@
+package structures
+
+import (
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// TEP-62 NFT ops
+const (
+	OpNftTransfer = 0x5fcc3d14
+)
+
+type (
+	NftTransfer struct {
+		_                   tlb.Magic        `tlb:"#5fcc3d14"`
+		QueryID             uint64           `tlb:"## 64"`
+		NewOwner            *address.Address `tlb:"addr"`
+		ResponseDestination *address.Address `tlb:"addr"`
+		CustomPayload       *cell.Cell       `tlb:"maybe ^"`
+		FwdTonAmount        tlb.Coins        `tlb:"."`
+		FwdPayload          *cell.Cell       `tlb:"either . ^"`
+	}
+)
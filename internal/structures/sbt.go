@@ -0,0 +1,34 @@
+package structures
+
+import (
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// TEP-85 soulbound token ops
+const (
+	OpSBTProveOwnership = 0x04ded148
+	OpSBTRevoke         = 0x6f89f5e3
+	OpSBTDestroy        = 0x1f04537a
+)
+
+type (
+	SBTProveOwnership struct {
+		_           tlb.Magic        `tlb:"#04ded148"`
+		QueryID     uint64           `tlb:"## 64"`
+		Dest        *address.Address `tlb:"addr"`
+		FwdPayload  *cell.Cell       `tlb:"^"`
+		WithContent bool             `tlb:"bool"`
+	}
+
+	SBTRevoke struct {
+		_       tlb.Magic `tlb:"#6f89f5e3"`
+		QueryID uint64    `tlb:"## 64"`
+	}
+
+	SBTDestroy struct {
+		_       tlb.Magic `tlb:"#1f04537a"`
+		QueryID uint64    `tlb:"## 64"`
+	}
+)
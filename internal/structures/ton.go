@@ -0,0 +1,7 @@
+package structures
+
+const (
+	OpTextComment      = 0x00000000
+	OpEncryptedComment = 0x2167da4b
+	OpBounced          = 0xffffffff // prefix of bounced message body
+)
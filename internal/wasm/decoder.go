@@ -0,0 +1,347 @@
+// Package wasm runs custom decoders compiled to WebAssembly in a sandbox.
+//
+// A decoder module must export:
+//
+//	memory
+//	opcode() i32  - message opcode handled by decoder
+//	decode() i32  - decodes current message, non-zero result is an error code
+//
+// and may import functions of the "ton" host module:
+//
+//	body() i32                                - handle of message body slice
+//	load_uint(slice i32, bits i32) i64        - loads up to 64 bits
+//	load_ref(slice i32) i32                   - handle of the next ref slice
+//	bits_left(slice i32) i32
+//	refs_left(slice i32) i32
+//	load_bytes(slice i32, ptr i32, n i32)     - copies n bytes to memory
+//	load_addr(slice i32, ptr i32, cap i32) i32 - writes address string, returns length
+//	src_addr(ptr i32, cap i32) i32            - message source address
+//	dst_addr(ptr i32, cap i32) i32            - message destination address
+//	emit(ptr i32, len i32)                    - emits event JSON
+//
+// Decoders are untrusted, so event types are namespaced by decoder name:
+// event of type "swap" emitted by dex.wasm is stored as "wasm:dex:swap".
+// Jetton master can't be set by decoders, it's set only by built-in
+// handlers which verify jetton wallets.
+//
+// Every message is decoded in a fresh module instance with limited memory
+// and execution time, failures of decoders never affect the scanner.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+
+	"github.com/qynonyq/ton_dev_go_hw3/internal/storage"
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+const (
+	hostModule = "ton"
+	// 64KiB pages, 16MiB per instance
+	memoryLimitPages = 256
+	callTimeout      = 100 * time.Millisecond
+	maxSlices        = 1024
+	maxEvents        = 16
+	maxEventSize     = 16 << 10
+)
+
+// Runtime compiles and runs wasm decoders.
+type Runtime struct {
+	rt wazero.Runtime
+}
+
+// Decoder is a TxHandler backed by wasm module.
+type Decoder struct {
+	name     string
+	opcode   uint32
+	rt       wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+type callStateKey struct{}
+
+// callState is the host side state of single decode call.
+type callState struct {
+	// decoder name, namespace of event types
+	decoder string
+	tx      *handler.Tx
+	slices  []*cell.Slice
+	events  []handler.Event
+}
+
+type event struct {
+	Type          string `json:"type"`
+	Opcode        uint32 `json:"opcode"`
+	Sender        string `json:"sender"`
+	Recipient     string `json:"recipient"`
+	NftItem       string `json:"nft_item"`
+	Amount        string `json:"amount"`
+	Comment       string `json:"comment"`
+	Payload       string `json:"payload"`
+	CustomPayload string `json:"custom_payload"`
+}
+
+func NewRuntime(ctx context.Context) (*Runtime, error) {
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCloseOnContextDone(true))
+
+	_, err := rt.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().WithFunc(hostBody).Export("body").
+		NewFunctionBuilder().WithFunc(hostLoadUint).Export("load_uint").
+		NewFunctionBuilder().WithFunc(hostLoadRef).Export("load_ref").
+		NewFunctionBuilder().WithFunc(hostBitsLeft).Export("bits_left").
+		NewFunctionBuilder().WithFunc(hostRefsLeft).Export("refs_left").
+		NewFunctionBuilder().WithFunc(hostLoadBytes).Export("load_bytes").
+		NewFunctionBuilder().WithFunc(hostLoadAddr).Export("load_addr").
+		NewFunctionBuilder().WithFunc(hostSrcAddr).Export("src_addr").
+		NewFunctionBuilder().WithFunc(hostDstAddr).Export("dst_addr").
+		NewFunctionBuilder().WithFunc(hostEmit).Export("emit").
+		Instantiate(ctx)
+	if err != nil {
+		_ = rt.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate host module: %w", err)
+	}
+
+	return &Runtime{rt: rt}, nil
+}
+
+func (r *Runtime) Close(ctx context.Context) error {
+	return r.rt.Close(ctx)
+}
+
+// LoadDir compiles every .wasm file in dir into decoder.
+func (r *Runtime) LoadDir(ctx context.Context, dir string) ([]*Decoder, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return nil, err
+	}
+
+	decoders := make([]*Decoder, 0, len(paths))
+	for _, path := range paths {
+		d, err := r.load(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		decoders = append(decoders, d)
+	}
+
+	return decoders, nil
+}
+
+func (r *Runtime) load(ctx context.Context, path string) (*Decoder, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := r.rt.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", path, err)
+	}
+
+	d := &Decoder{
+		name:     storage.DecoderEventPrefix + strings.TrimSuffix(filepath.Base(path), ".wasm"),
+		rt:       r.rt,
+		compiled: compiled,
+	}
+
+	res, err := d.call(ctx, &callState{}, "opcode")
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get opcode: %w", d.name, err)
+	}
+	d.opcode = uint32(res)
+
+	return d, nil
+}
+
+func (d *Decoder) Name() string {
+	return d.name
+}
+
+func (d *Decoder) Opcode() uint32 {
+	return d.opcode
+}
+
+// Handle never returns error, decoders are untrusted and
+// their failures shouldn't stop block processing.
+func (d *Decoder) Handle(ctx context.Context, tx *handler.Tx) ([]handler.Event, error) {
+	state := &callState{decoder: d.name, tx: tx}
+	res, err := d.call(ctx, state, "decode")
+	if err != nil {
+		logrus.Warnf("[WSM] %s failed: %s", d.name, err)
+		return nil, nil
+	}
+	if res != 0 {
+		logrus.Debugf("[WSM] %s returned code %d", d.name, int32(res))
+		return nil, nil
+	}
+
+	return state.events, nil
+}
+
+func (d *Decoder) call(ctx context.Context, state *callState, fn string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, callStateKey{}, state)
+
+	mod, err := d.rt.InstantiateModule(ctx, d.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return 0, err
+	}
+	defer mod.Close(ctx)
+
+	f := mod.ExportedFunction(fn)
+	if f == nil {
+		return 0, fmt.Errorf("function %s is not exported", fn)
+	}
+	res, err := f.Call(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 1 {
+		return 0, fmt.Errorf("function %s returned %d results", fn, len(res))
+	}
+
+	return res[0], nil
+}
+
+var errNoMessage = errors.New("no message")
+
+// host functions panic on misuse, wazero turns panics into errors of the call
+func state(ctx context.Context) *callState {
+	s, _ := ctx.Value(callStateKey{}).(*callState)
+	if s == nil || s.tx == nil {
+		panic(errNoMessage)
+	}
+	return s
+}
+
+func (s *callState) slice(h uint32) *cell.Slice {
+	if int(h) >= len(s.slices) {
+		panic(fmt.Errorf("invalid slice handle %d", h))
+	}
+	return s.slices[h]
+}
+
+func (s *callState) addSlice(sl *cell.Slice) uint32 {
+	if len(s.slices) >= maxSlices {
+		panic(errors.New("too many slices"))
+	}
+	s.slices = append(s.slices, sl)
+	return uint32(len(s.slices) - 1)
+}
+
+func write(m api.Module, ptr, capacity uint32, data []byte) uint32 {
+	if uint32(len(data)) > capacity {
+		panic(fmt.Errorf("buffer of %d bytes is too small", capacity))
+	}
+	if !m.Memory().Write(ptr, data) {
+		panic(errors.New("write out of memory range"))
+	}
+	return uint32(len(data))
+}
+
+func hostBody(ctx context.Context) uint32 {
+	s := state(ctx)
+	return s.addSlice(s.tx.Msg.Body.BeginParse())
+}
+
+func hostLoadUint(ctx context.Context, h, bits uint32) uint64 {
+	if bits > 64 {
+		panic(fmt.Errorf("can't load %d bits", bits))
+	}
+	v, err := state(ctx).slice(h).LoadUInt(uint(bits))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func hostLoadRef(ctx context.Context, h uint32) uint32 {
+	s := state(ctx)
+	ref, err := s.slice(h).LoadRef()
+	if err != nil {
+		panic(err)
+	}
+	return s.addSlice(ref)
+}
+
+func hostBitsLeft(ctx context.Context, h uint32) uint32 {
+	return uint32(state(ctx).slice(h).BitsLeft())
+}
+
+func hostRefsLeft(ctx context.Context, h uint32) uint32 {
+	return uint32(state(ctx).slice(h).RefsNum())
+}
+
+func hostLoadBytes(ctx context.Context, m api.Module, h, ptr, n uint32) {
+	data, err := state(ctx).slice(h).LoadSlice(uint(n) * 8)
+	if err != nil {
+		panic(err)
+	}
+	write(m, ptr, n, data)
+}
+
+func hostLoadAddr(ctx context.Context, m api.Module, h, ptr, capacity uint32) uint32 {
+	addr, err := state(ctx).slice(h).LoadAddr()
+	if err != nil {
+		panic(err)
+	}
+	return write(m, ptr, capacity, []byte(addr.String()))
+}
+
+func hostSrcAddr(ctx context.Context, m api.Module, ptr, capacity uint32) uint32 {
+	return write(m, ptr, capacity, []byte(state(ctx).tx.Msg.SrcAddr.String()))
+}
+
+func hostDstAddr(ctx context.Context, m api.Module, ptr, capacity uint32) uint32 {
+	return write(m, ptr, capacity, []byte(state(ctx).tx.Msg.DstAddr.String()))
+}
+
+func hostEmit(ctx context.Context, m api.Module, ptr, size uint32) {
+	s := state(ctx)
+	if len(s.events) >= maxEvents {
+		panic(errors.New("too many events"))
+	}
+	if size > maxEventSize {
+		panic(fmt.Errorf("event of %d bytes is too big", size))
+	}
+	data, ok := m.Memory().Read(ptr, size)
+	if !ok {
+		panic(errors.New("read out of memory range"))
+	}
+
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		panic(fmt.Errorf("invalid event json: %w", err))
+	}
+	if e.Type == "" || strings.Contains(e.Type, ":") {
+		panic(fmt.Errorf("invalid event type %q", e.Type))
+	}
+
+	s.events = append(s.events, handler.Event{
+		Type:          s.decoder + ":" + e.Type,
+		Opcode:        e.Opcode,
+		Sender:        e.Sender,
+		Recipient:     e.Recipient,
+		NftItem:       e.NftItem,
+		Amount:        e.Amount,
+		Comment:       e.Comment,
+		Payload:       e.Payload,
+		CustomPayload: e.CustomPayload,
+	})
+}
@@ -0,0 +1,304 @@
+// Package client is a Go client of the scanner REST API,
+// see internal/api/openapi.json for the specification.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is returned for non-2xx responses.
+type Error struct {
+	StatusCode int
+	Message    string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	adminToken string
+}
+
+// New returns client of api at baseURL, e.g. http://localhost:8080.
+// Default http client is used if httpClient is nil.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    httpClient,
+	}
+}
+
+// SetAdminToken sets token sent with requests, required by admin endpoints.
+func (c *Client) SetAdminToken(token string) {
+	c.adminToken = token
+}
+
+func (c *Client) Events(ctx context.Context, p EventsParams) ([]Event, error) {
+	q := url.Values{}
+	setString(q, "type", p.Type)
+	if p.Opcode != nil {
+		q.Set("opcode", strconv.FormatUint(uint64(*p.Opcode), 10))
+	}
+	setString(q, "jetton_master", p.JettonMaster)
+	setString(q, "min_amount", p.MinAmount)
+	setString(q, "max_amount", p.MaxAmount)
+	if p.Success != nil {
+		q.Set("success", strconv.FormatBool(*p.Success))
+	}
+	if p.IncludeDeleted {
+		q.Set("include_deleted", "true")
+	}
+	setPage(q, p.AfterID, p.Limit)
+
+	var resp struct {
+		Events []Event `json:"events"`
+	}
+	if err := c.get(ctx, "/events", q, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Events, nil
+}
+
+func (c *Client) Summaries(ctx context.Context, p SummariesParams) ([]EventSummary, error) {
+	q := url.Values{}
+	setString(q, "label", p.Label)
+	setString(q, "type", p.Type)
+	setPage(q, p.AfterID, p.Limit)
+
+	var resp struct {
+		Summaries []EventSummary `json:"summaries"`
+	}
+	if err := c.get(ctx, "/summaries", q, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Summaries, nil
+}
+
+func (c *Client) Consumers(ctx context.Context) ([]ConsumerLag, error) {
+	var resp struct {
+		Consumers []ConsumerLag `json:"consumers"`
+	}
+	if err := c.get(ctx, "/consumers", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Consumers, nil
+}
+
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var resp Status
+	if err := c.get(ctx, "/status", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Stats returns per-minute throughput, zero times use server defaults.
+func (c *Client) Stats(ctx context.Context, from, to time.Time) ([]Stat, error) {
+	q := url.Values{}
+	if !from.IsZero() {
+		q.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		q.Set("to", to.Format(time.RFC3339))
+	}
+
+	var resp struct {
+		Stats []Stat `json:"stats"`
+	}
+	if err := c.get(ctx, "/stats", q, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Stats, nil
+}
+
+func (c *Client) Handlers(ctx context.Context) ([]HandlerStats, error) {
+	var resp struct {
+		Handlers []HandlerStats `json:"handlers"`
+	}
+	if err := c.get(ctx, "/handlers", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Handlers, nil
+}
+
+// SubscribeAccount watches account and backfills its history since from,
+// requires admin token. History is backfilled not deeper than the server
+// allows, zero from backfills as deep as allowed. Backfill runs in
+// background, poll AccountWatch for progress.
+func (c *Client) SubscribeAccount(ctx context.Context, address string, from time.Time) (*AccountWatch, error) {
+	req := struct {
+		Address  string     `json:"address"`
+		FromTime *time.Time `json:"from_time,omitempty"`
+	}{Address: address}
+	if !from.IsZero() {
+		req.FromTime = &from
+	}
+
+	var resp AccountWatch
+	if err := c.post(ctx, "/accounts/subscriptions", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *Client) AccountWatch(ctx context.Context, address string) (*AccountWatch, error) {
+	var resp AccountWatch
+	if err := c.get(ctx, "/accounts/subscriptions/"+url.PathEscape(address), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// FailedBlocks returns dead-letter blocks, resolved ones too if resolved is set.
+func (c *Client) FailedBlocks(ctx context.Context, resolved bool, limit int) ([]FailedBlock, error) {
+	q := url.Values{}
+	if resolved {
+		q.Set("resolved", "true")
+	}
+	setPage(q, 0, limit)
+
+	var resp struct {
+		FailedBlocks []FailedBlock `json:"failed_blocks"`
+	}
+	if err := c.get(ctx, "/admin/failed-blocks", q, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.FailedBlocks, nil
+}
+
+// RerunFailedBlock processes failed block again with chosen handlers.
+func (c *Client) RerunFailedBlock(ctx context.Context, seqno uint32, p RerunParams) (*FailedBlock, error) {
+	var resp FailedBlock
+	path := "/admin/failed-blocks/" + strconv.FormatUint(uint64(seqno), 10) + "/rerun"
+	if err := c.post(ctx, path, p, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// PurgeAddress removes all indexed data of address, the address isn't
+// indexed afterwards.
+func (c *Client) PurgeAddress(ctx context.Context, address string) (*Deletion, error) {
+	var resp Deletion
+	if err := c.send(ctx, http.MethodDelete, "/admin/addresses/"+url.PathEscape(address), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, q url.Values, dst any) error {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	c.authorize(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return decodeError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+func (c *Client) post(ctx context.Context, path string, body, dst any) error {
+	return c.send(ctx, http.MethodPost, path, body, dst)
+}
+
+// send makes request with JSON body, nil body is not sent.
+func (c *Client) send(ctx context.Context, method, path string, body, dst any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.authorize(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return decodeError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+		apiErr.Message = resp.Status
+	}
+
+	return apiErr
+}
+
+func setString(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
+
+func setPage(q url.Values, afterID uint64, limit int) {
+	if afterID > 0 {
+		q.Set("after_id", strconv.FormatUint(afterID, 10))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+}
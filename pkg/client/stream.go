@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StreamMessage is either an event or a correction of already streamed block.
+type StreamMessage struct {
+	// resume token of event, empty for corrections
+	Token      string
+	Event      *Event
+	Correction *Correction
+}
+
+// ErrStreamClosed is returned when server closed the stream,
+// e.g. because consumer was too slow. Reconnect with the last token.
+var ErrStreamClosed = errors.New("stream closed by server")
+
+// Stream reads /events/stream after token (empty for live events only) and
+// calls fn for every message until ctx is done, fn fails or stream breaks.
+// Keep the last message token to resume without gaps.
+func (c *Client) Stream(ctx context.Context, token, consumer string, fn func(StreamMessage) error) error {
+	q := url.Values{}
+	setString(q, "token", token)
+	setString(q, "consumer", consumer)
+	u := c.baseURL + "/events/stream"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return decodeError(resp)
+	}
+
+	var (
+		scanner = bufio.NewScanner(resp.Body)
+		id      string
+		event   string
+		data    strings.Builder
+	)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := dispatch(id, event, data.String(), fn); err != nil {
+					return err
+				}
+			}
+			id, event = "", ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// keepalive
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data.WriteString(strings.TrimPrefix(line, "data: "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return ErrStreamClosed
+}
+
+func dispatch(id, event, data string, fn func(StreamMessage) error) error {
+	switch event {
+	case "error":
+		apiErr := &Error{StatusCode: http.StatusOK}
+		if err := json.Unmarshal([]byte(data), apiErr); err != nil {
+			return err
+		}
+		return apiErr
+	case "correction":
+		var c Correction
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return err
+		}
+		return fn(StreamMessage{Correction: &c})
+	default:
+		var e Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return err
+		}
+		return fn(StreamMessage{Token: id, Event: &e})
+	}
+}
@@ -0,0 +1,182 @@
+package client
+
+import "time"
+
+// Types mirror schemas of internal/api/openapi.json.
+
+type Event struct {
+	ID               uint64     `json:"id"`
+	Type             string     `json:"type"`
+	SeqNo            uint32     `json:"seqno"`
+	EventIndex       uint32     `json:"event_index"`
+	LT               uint64     `json:"lt"`
+	UTime            uint32     `json:"utime"`
+	TxHash           string     `json:"tx_hash"`
+	Opcode           uint32     `json:"opcode"`
+	JettonMaster     string     `json:"jetton_master,omitempty"`
+	JettonWalletCode string     `json:"jetton_wallet_code,omitempty"`
+	JettonWalletType string     `json:"jetton_wallet_type,omitempty"`
+	Sender           string     `json:"sender"`
+	Recipient        string     `json:"recipient"`
+	NftItem          string     `json:"nft_item,omitempty"`
+	Amount           string     `json:"amount"`
+	Comment          string     `json:"comment,omitempty"`
+	Payload          string     `json:"payload,omitempty"`
+	CustomPayload    string     `json:"custom_payload,omitempty"`
+	ConfigParam      *int32     `json:"config_param,omitempty"`
+	ConfigValue      string     `json:"config_value,omitempty"`
+	Success          bool       `json:"success"`
+	Revision         uint32     `json:"revision"`
+	CreatedAt        time.Time  `json:"created_at"`
+	DeletedAt        *time.Time `json:"deleted_at"`
+}
+
+// Correction replaces events of already streamed block. Blocks filled
+// after gaps arrive as corrections without superseded events.
+// Corrections are sent only to connected streams, they are not replayed
+// after reconnect.
+type Correction struct {
+	SeqNo      uint32  `json:"seqno"`
+	Superseded []Event `json:"superseded"`
+	Events     []Event `json:"events"`
+}
+
+type EventSummary struct {
+	ID        uint64    `json:"id"`
+	SeqNo     uint32    `json:"seqno"`
+	Label     string    `json:"label"`
+	Address   string    `json:"address"`
+	Type      string    `json:"type"`
+	Count     uint32    `json:"count"`
+	Amount    string    `json:"amount"`
+	FirstLT   uint64    `json:"first_lt"`
+	LastLT    uint64    `json:"last_lt"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConsumerLag struct {
+	Consumer    string    `json:"consumer"`
+	Delivered   uint32    `json:"delivered_seqno"`
+	Head        uint32    `json:"head_seqno"`
+	Lag         uint32    `json:"lag"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+type Gap struct {
+	From uint32 `json:"from"`
+	To   uint32 `json:"to"`
+}
+
+type Status struct {
+	LastSeqNo       uint32             `json:"last_seqno"`
+	LastProcessedAt time.Time          `json:"last_processed_at"`
+	Gaps            []Gap              `json:"gaps"`
+	Concurrency     []ConcurrencyStats `json:"concurrency"`
+	Jobs            []JobStatus        `json:"jobs"`
+}
+
+// JobStatus describes runs of periodic scanner job since start.
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Interval     time.Duration `json:"interval_ns"`
+	Enabled      bool          `json:"enabled"`
+	Running      bool          `json:"running"`
+	Runs         uint64        `json:"runs"`
+	Failures     uint64        `json:"failures"`
+	LastStart    time.Time     `json:"last_start,omitempty"`
+	LastDuration time.Duration `json:"last_duration_ns"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run,omitempty"`
+}
+
+type ConcurrencyStats struct {
+	Name     string        `json:"name"`
+	Limit    int           `json:"limit"`
+	InFlight int           `json:"in_flight"`
+	Baseline time.Duration `json:"baseline_latency_ns"`
+}
+
+type Stat struct {
+	Minute        time.Time `json:"minute"`
+	Blocks        uint64    `json:"blocks"`
+	Txs           uint64    `json:"txs"`
+	Events        uint64    `json:"events"`
+	LiteserverReq uint64    `json:"liteserver_requests"`
+	Suppressed    uint64    `json:"suppressed"`
+}
+
+type HandlerStats struct {
+	Name     string        `json:"name"`
+	Critical bool          `json:"critical"`
+	Shadow   bool          `json:"shadow"`
+	Calls    uint64        `json:"calls"`
+	Errors   uint64        `json:"errors"`
+	Invalid  uint64        `json:"invalid_events"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// AccountWatch is a subscribed account with progress of its history backfill.
+type AccountWatch struct {
+	Address   string    `json:"address"`
+	FromTime  time.Time `json:"from_time"`
+	Status    string    `json:"status"`
+	Txs       uint32    `json:"txs"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FailedBlock is a dead-letter block which transactions failed to process.
+type FailedBlock struct {
+	SeqNo         uint32     `json:"seqno"`
+	Handler       string     `json:"handler,omitempty"`
+	Error         string     `json:"error"`
+	Attempts      uint32     `json:"attempts"`
+	RerunHandlers string     `json:"rerun_handlers,omitempty"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// RerunParams select handlers for rerun of failed block. If Handlers
+// is set only they are run, Disabled handlers are never run. Unknown
+// handler names are rejected.
+type RerunParams struct {
+	Handlers []string `json:"handlers,omitempty"`
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// Deletion is an audit record of address data removal,
+// address is kept only as SHA-256 of its raw form.
+type Deletion struct {
+	ID            uint64    `json:"id"`
+	AddressHash   string    `json:"address_hash"`
+	Events        int64     `json:"events"`
+	ShadowEvents  int64     `json:"shadow_events"`
+	Summaries     int64     `json:"summaries"`
+	Watches       int64     `json:"watches"`
+	WalletCodes   int64     `json:"wallet_codes"`
+	SinkJobEvents int64     `json:"sink_job_events"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// EventsParams filters events, zero values are not sent.
+type EventsParams struct {
+	Type           string
+	Opcode         *uint32
+	JettonMaster   string
+	MinAmount      string
+	MaxAmount      string
+	Success        *bool
+	IncludeDeleted bool
+	AfterID        uint64
+	Limit          int
+}
+
+// SummariesParams filters summaries, zero values are not sent.
+type SummariesParams struct {
+	Label   string
+	Type    string
+	AfterID uint64
+	Limit   int
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// Outcome is the effect of transaction, either emulated or observed on chain.
+type Outcome struct {
+	Success  bool
+	ExitCode int32
+	OutMsgs  int
+}
+
+// Emulator emulates incoming message of transaction on account state
+// before it. The scanner has no built-in emulator, plugins can set one,
+// e.g. backed by TVM emulator library bindings.
+type Emulator interface {
+	Emulate(ctx context.Context, tx *Tx) (*Outcome, error)
+}
+
+// ObservedOutcome returns outcome of transaction included in block.
+func ObservedOutcome(tx *tlb.Transaction) Outcome {
+	o := Outcome{Success: true, OutMsgs: int(tx.OutMsgCount)}
+
+	desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok {
+		return o
+	}
+	if desc.Aborted {
+		o.Success = false
+	}
+	if compute, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseVM); ok {
+		o.ExitCode = compute.Details.ExitCode
+		if !compute.Success {
+			o.Success = false
+		}
+	}
+	if desc.ActionPhase != nil && !desc.ActionPhase.Success {
+		o.Success = false
+	}
+
+	return o
+}
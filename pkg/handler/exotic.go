@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+const (
+	libraryFetchAttempts = 3
+	libraryRetryDelay    = 500 * time.Millisecond
+)
+
+// UnresolvedCellError is returned for exotic cells which can't be
+// turned into ordinary ones, e.g. pruned branches or unknown libraries.
+type UnresolvedCellError struct {
+	Type cell.Type
+	Hash []byte
+}
+
+func (e *UnresolvedCellError) Error() string {
+	return fmt.Sprintf("unresolved exotic cell [type=%s] [hash=%s]", CellTypeName(e.Type), hex.EncodeToString(e.Hash))
+}
+
+// LibraryFetchError is returned when library cell can't be loaded from
+// liteserver after retries. Unlike UnresolvedCellError it's transient,
+// scanner fails the block to process it again later.
+type LibraryFetchError struct {
+	Hash []byte
+	Err  error
+}
+
+func (e *LibraryFetchError) Error() string {
+	return fmt.Sprintf("failed to get library %s: %s", hex.EncodeToString(e.Hash), e.Err)
+}
+
+func (e *LibraryFetchError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveCell returns ordinary cell to parse instead of c. Library cells
+// are loaded from liteserver, merkle proofs and updates are replaced with
+// their (new) virtual root. Ordinary cells are returned as is.
+func ResolveCell(ctx context.Context, api ton.APIClientWrapped, c *cell.Cell) (*cell.Cell, error) {
+	for {
+		switch typ := c.GetType(); typ {
+		case cell.OrdinaryCellType:
+			return c, nil
+		case cell.LibraryCellType:
+			sl := c.BeginParse()
+			if _, err := sl.LoadUInt(8); err != nil {
+				return nil, err
+			}
+			hash, err := sl.LoadSlice(256)
+			if err != nil {
+				return nil, err
+			}
+			lib, err := getLibrary(ctx, api, hash)
+			if err != nil {
+				return nil, err
+			}
+			if lib == nil {
+				return nil, &UnresolvedCellError{Type: typ, Hash: hash}
+			}
+			c = lib
+		case cell.MerkleProofCellType:
+			ref, err := c.PeekRef(0)
+			if err != nil {
+				return nil, err
+			}
+			c = ref
+		case cell.MerkleUpdateCellType:
+			ref, err := c.PeekRef(1)
+			if err != nil {
+				return nil, err
+			}
+			c = ref
+		default:
+			return nil, &UnresolvedCellError{Type: typ, Hash: c.Hash()}
+		}
+	}
+}
+
+// getLibrary loads library cell by hash, nil if liteserver doesn't know
+// it. Failed requests are retried, so a single liteserver error doesn't
+// leave the cell unresolved.
+func getLibrary(ctx context.Context, api ton.APIClientWrapped, hash []byte) (*cell.Cell, error) {
+	var err error
+	for attempt := 0; attempt < libraryFetchAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(libraryRetryDelay << (attempt - 1)):
+			}
+		}
+
+		var libs []*cell.Cell
+		libs, err = api.GetLibraries(ctx, hash)
+		if err == nil {
+			if len(libs) == 0 {
+				return nil, nil
+			}
+			return libs[0], nil
+		}
+	}
+
+	return nil, &LibraryFetchError{Hash: hash, Err: err}
+}
+
+func CellTypeName(t cell.Type) string {
+	switch t {
+	case cell.OrdinaryCellType:
+		return "ordinary"
+	case cell.PrunedCellType:
+		return "pruned"
+	case cell.LibraryCellType:
+		return "library"
+	case cell.MerkleProofCellType:
+		return "merkle_proof"
+	case cell.MerkleUpdateCellType:
+		return "merkle_update"
+	default:
+		return "unknown"
+	}
+}
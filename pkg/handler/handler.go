@@ -0,0 +1,44 @@
+// Package handler defines decoders of transactions into events.
+// It's public, so handlers can be built as Go plugins outside of the scanner.
+package handler
+
+import (
+	"context"
+
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// Tx is an incoming internal message transaction passed to handlers.
+type Tx struct {
+	// master block which includes transaction
+	Master *ton.BlockIDExt
+	API    ton.APIClientWrapped
+	Tx     *tlb.Transaction
+	Msg    *tlb.InternalMessage
+	// first 32 bits of message body, 0 if body is shorter
+	Opcode uint32
+}
+
+// Event is a decoded event, scanner fills block and transaction data itself.
+// Payload and CustomPayload are base64 BOCs of forward and custom payloads
+// handler couldn't decode, so consumers can decode them themselves.
+type Event struct {
+	Type             string
+	Opcode           uint32
+	JettonMaster     string
+	JettonWalletCode string
+	JettonWalletType string
+	Sender           string
+	Recipient        string
+	NftItem          string
+	Amount           string
+	Comment          string
+	Payload          string
+	CustomPayload    string
+}
+
+type TxHandler interface {
+	Name() string
+	Handle(ctx context.Context, tx *Tx) ([]Event, error)
+}
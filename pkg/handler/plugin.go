@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+)
+
+// RegisterSymbol is a function every plugin must export:
+//
+//	func Register(r *handler.Registry) error
+const RegisterSymbol = "Register"
+
+// LoadPlugins opens every .so file in dir and calls its Register function.
+func LoadPlugins(dir string, r *Registry) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if err := loadPlugin(path, r); err != nil {
+			return loaded, err
+		}
+		loaded = append(loaded, filepath.Base(path))
+	}
+
+	return loaded, nil
+}
+
+func loadPlugin(path string, r *Registry) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin %s: %w", path, err)
+	}
+	sym, err := p.Lookup(RegisterSymbol)
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", path, err)
+	}
+	register, ok := sym.(func(*Registry) error)
+	if !ok {
+		return fmt.Errorf("plugin %s: %s has type %T, expected func(*handler.Registry) error",
+			path, RegisterSymbol, sym)
+	}
+	if err := register(r); err != nil {
+		return fmt.Errorf("plugin %s: failed to register: %w", path, err)
+	}
+
+	return nil
+}
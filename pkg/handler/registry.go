@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+// Registry keeps handlers registered against message opcodes
+// or code hashes of receiving contracts.
+type Registry struct {
+	mu       sync.RWMutex
+	byOpcode map[uint32][]TxHandler
+	byCode   map[string][]TxHandler
+	emulator Emulator
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byOpcode: make(map[uint32][]TxHandler),
+		byCode:   make(map[string][]TxHandler),
+	}
+}
+
+func (r *Registry) RegisterOpcode(op uint32, h TxHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byOpcode[op] = append(r.byOpcode[op], h)
+}
+
+func (r *Registry) RegisterCodeHash(hash []byte, h TxHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := hex.EncodeToString(hash)
+	r.byCode[key] = append(r.byCode[key], h)
+}
+
+// SetEmulator sets emulator used to cross-check outcomes of
+// transactions handlers decoded events from.
+func (r *Registry) SetEmulator(e Emulator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emulator = e
+}
+
+func (r *Registry) Emulator() Emulator {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.emulator
+}
+
+// HasCodeHandlers reports whether code hash of receiving contract is needed.
+func (r *Registry) HasCodeHandlers() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.byCode) > 0
+}
+
+// Names returns sorted names of registered handlers.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for _, list := range r.byOpcode {
+		for _, h := range list {
+			seen[h.Name()] = struct{}{}
+		}
+	}
+	for _, list := range r.byCode {
+		for _, h := range list {
+			seen[h.Name()] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Handlers returns handlers for opcode and code hash, codeHash may be nil.
+func (r *Registry) Handlers(op uint32, codeHash []byte) []TxHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handlers := append([]TxHandler(nil), r.byOpcode[op]...)
+	if codeHash != nil {
+		handlers = append(handlers, r.byCode[hex.EncodeToString(codeHash)]...)
+	}
+
+	return handlers
+}
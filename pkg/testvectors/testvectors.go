@@ -0,0 +1,179 @@
+// Package testvectors is a corpus of incoming message bodies with golden
+// events, so decoders can be developed and checked without liteserver.
+// Vectors captured from chain with cmd/capture-vector record hash of
+// their transaction, the rest are synthetic: bodies are built by hand
+// from TEP schemas of jettons, NFTs and SBTs, and addresses don't refer
+// to real contracts. Vectors without events document messages scanner
+// doesn't decode (yet).
+package testvectors
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+
+	"github.com/qynonyq/ton_dev_go_hw3/pkg/handler"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+var defaultAmount = tlb.MustFromTON("0.05")
+
+// Vector is an internal message delivered to Dst with events it decodes to.
+// Jetton master and wallet fields depend on chain state, they are left
+// empty in vectors and are compared only when set.
+type Vector struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	TxHash      string          `json:"tx_hash,omitempty"` // hex, set for captured vectors
+	Src         string          `json:"src"`
+	Dst         string          `json:"dst"`
+	Bounced     bool            `json:"bounced,omitempty"`
+	Amount      string          `json:"amount,omitempty"` // nanotons, defaults to 0.05 TON
+	Body        string          `json:"body"`             // base64 BOC
+	Events      []handler.Event `json:"events"`
+}
+
+// All returns vectors of the corpus.
+func All() ([]Vector, error) {
+	var vectors []Vector
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		return nil, fmt.Errorf("failed to decode vectors: %w", err)
+	}
+
+	return vectors, nil
+}
+
+// Get returns vector by name.
+func Get(name string) (Vector, error) {
+	vectors, err := All()
+	if err != nil {
+		return Vector{}, err
+	}
+	for _, v := range vectors {
+		if v.Name == name {
+			return v, nil
+		}
+	}
+
+	return Vector{}, fmt.Errorf("vector %q not found", name)
+}
+
+// Tx builds transaction to pass to handlers. Master is a placeholder
+// block and api is nil, handlers which query chain state need api and
+// master set by caller or the state cached beforehand.
+func (v Vector) Tx() (*handler.Tx, error) {
+	boc, err := base64.StdEncoding.DecodeString(v.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode body of %s: %w", v.Name, err)
+	}
+	body, err := cell.FromBOC(boc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse body of %s: %w", v.Name, err)
+	}
+	src, err := address.ParseAddr(v.Src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid src of %s: %w", v.Name, err)
+	}
+	dst, err := address.ParseAddr(v.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dst of %s: %w", v.Name, err)
+	}
+
+	amount := defaultAmount
+	if v.Amount != "" {
+		if amount, err = tlb.FromNanoTONStr(v.Amount); err != nil {
+			return nil, fmt.Errorf("invalid amount of %s: %w", v.Name, err)
+		}
+	}
+
+	msg := &tlb.InternalMessage{
+		Bounce:  !v.Bounced,
+		Bounced: v.Bounced,
+		SrcAddr: src,
+		DstAddr: dst,
+		Amount:  amount,
+		Body:    body,
+	}
+	// stable hash, so logs of different runs can be matched
+	hash := sha256.Sum256([]byte(v.Name))
+	if v.TxHash != "" {
+		h, err := hex.DecodeString(v.TxHash)
+		if err != nil || len(h) != len(hash) {
+			return nil, fmt.Errorf("invalid tx hash of %s", v.Name)
+		}
+		copy(hash[:], h)
+	}
+	tx := &handler.Tx{
+		Master: &ton.BlockIDExt{Workchain: address.MasterchainID, Shard: math.MinInt64},
+		Tx: &tlb.Transaction{
+			AccountAddr: dst.Data(),
+			Hash:        hash[:],
+			IO: struct {
+				In  *tlb.Message      `tlb:"maybe ^"`
+				Out *tlb.MessagesList `tlb:"maybe ^"`
+			}{In: &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: msg}},
+		},
+		Msg: msg,
+	}
+	if op, err := body.BeginParse().LoadUInt(32); err == nil {
+		tx.Opcode = uint32(op)
+	}
+
+	return tx, nil
+}
+
+// Compare checks events decoded from vector against golden ones.
+func (v Vector) Compare(got []handler.Event) error {
+	if len(got) != len(v.Events) {
+		return fmt.Errorf("%s: expected %d events, got %d", v.Name, len(v.Events), len(got))
+	}
+
+	var diffs []string
+	for i, want := range v.Events {
+		g := got[i]
+		if want.Opcode != g.Opcode {
+			diffs = append(diffs, fmt.Sprintf("event %d Opcode: expected %x, got %x", i, want.Opcode, g.Opcode))
+		}
+		for _, f := range []struct {
+			name      string
+			want, got string
+			chain     bool
+		}{
+			{"Type", want.Type, g.Type, false},
+			{"JettonMaster", want.JettonMaster, g.JettonMaster, true},
+			{"JettonWalletCode", want.JettonWalletCode, g.JettonWalletCode, true},
+			{"JettonWalletType", want.JettonWalletType, g.JettonWalletType, true},
+			{"Sender", want.Sender, g.Sender, false},
+			{"Recipient", want.Recipient, g.Recipient, false},
+			{"NftItem", want.NftItem, g.NftItem, false},
+			{"Amount", want.Amount, g.Amount, false},
+			{"Comment", want.Comment, g.Comment, false},
+			{"Payload", want.Payload, g.Payload, false},
+			{"CustomPayload", want.CustomPayload, g.CustomPayload, false},
+		} {
+			if f.chain && f.want == "" {
+				continue
+			}
+			if f.want != f.got {
+				diffs = append(diffs, fmt.Sprintf("event %d %s: expected %q, got %q", i, f.name, f.want, f.got))
+			}
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%s: %s", v.Name, strings.Join(diffs, "; "))
+	}
+
+	return nil
+}